@@ -21,12 +21,20 @@ package builder
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"path"
 	"sort"
 	"strings"
 
+	log "github.com/sirupsen/logrus"
+
 	"mynewt.apache.org/newt/newt/parse"
+	"mynewt.apache.org/newt/newt/pkg"
+	"mynewt.apache.org/newt/newt/project"
 	"mynewt.apache.org/newt/newt/resolve"
+	"mynewt.apache.org/newt/util"
 )
 
 type DepEntry struct {
@@ -183,6 +191,74 @@ func DepGraphText(graph DepGraph) string {
 	return buffer.String()
 }
 
+func depGraphTreeNode(buffer *bytes.Buffer, graph DepGraph, entry DepEntry,
+	prefix string, last bool, expanded map[string]struct{}) {
+
+	branch := "|-- "
+	childPrefix := prefix + "|   "
+	if last {
+		branch = "`-- "
+		childPrefix = prefix + "    "
+	}
+
+	fmt.Fprintf(buffer, "\n%s%s%s", prefix, branch, depString(entry))
+
+	children := graph[entry.PkgName]
+	if len(children) == 0 {
+		return
+	}
+
+	// Only expand a package's subtree the first time it is encountered.
+	if _, ok := expanded[entry.PkgName]; ok {
+		fmt.Fprintf(buffer, " (*)")
+		return
+	}
+	expanded[entry.PkgName] = struct{}{}
+
+	for i, child := range children {
+		depGraphTreeNode(buffer, graph, child, childPrefix,
+			i == len(children)-1, expanded)
+	}
+}
+
+// DepGraphTree renders a dependency graph as an indented tree rooted at each
+// of the specified packages.  A subtree is only expanded the first time it
+// appears; subsequent appearances are marked with "(*)".
+func DepGraphTree(graph DepGraph, roots []string) string {
+	return depGraphTree("Dependency tree:", graph, roots)
+}
+
+// RevdepGraphTree renders a reverse-dependency graph as an indented tree
+// rooted at each of the specified packages; each package's children are the
+// packages that depend on it.
+func RevdepGraphTree(graph DepGraph, roots []string) string {
+	return depGraphTree("Reverse-dependency tree:", graph, roots)
+}
+
+func depGraphTree(title string, graph DepGraph, roots []string) string {
+	buffer := bytes.NewBufferString("")
+	expanded := map[string]struct{}{}
+
+	fmt.Fprintf(buffer, "%s", title)
+	for _, root := range roots {
+		fmt.Fprintf(buffer, "\n%s", root)
+
+		children := graph[root]
+		if _, ok := expanded[root]; ok && len(children) > 0 {
+			fmt.Fprintf(buffer, " (*)")
+			continue
+		}
+		expanded[root] = struct{}{}
+
+		for i, child := range children {
+			depGraphTreeNode(buffer, graph, child, "",
+				i == len(children)-1, expanded)
+		}
+	}
+
+	return buffer.String()
+}
+
 func DepGraphViz(graph DepGraph) string {
 	parents := make([]string, 0, len(graph))
 	for pname, _ := range graph {
@@ -249,6 +325,207 @@ func RevdepGraphViz(graph DepGraph) string {
 	return buffer.String()
 }
 
+// Output formats supported by DepGraphRender() and RevdepGraphRender().
+const (
+	DEP_GRAPH_FORMAT_TEXT = "text"
+	DEP_GRAPH_FORMAT_DOT  = "dot"
+	DEP_GRAPH_FORMAT_JSON = "json"
+)
+
+var DepGraphFormats = []string{
+	DEP_GRAPH_FORMAT_TEXT,
+	DEP_GRAPH_FORMAT_DOT,
+	DEP_GRAPH_FORMAT_JSON,
+}
+
+type depGraphJsonEntry struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition,omitempty"`
+}
+
+// Converts a graph to JSON: an object mapping each parent to an array of its
+// children.
+func depGraphJson(graph DepGraph) (string, error) {
+	m := make(map[string][]depGraphJsonEntry, len(graph))
+	for pname, children := range graph {
+		entries := make([]depGraphJsonEntry, len(children))
+		for i, child := range children {
+			entries[i] = depGraphJsonEntry{
+				Name: child.PkgName,
+				Condition: strings.TrimPrefix(depString(child),
+					child.PkgName),
+			}
+		}
+		m[pname] = entries
+	}
+
+	j, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return "", util.ChildNewtError(err)
+	}
+
+	return string(j) + "\n", nil
+}
+
+// DepInventoryEntry describes one package in a flat dependency inventory.
+type DepInventoryEntry struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Repo    string `json:"repo"`
+	Version string `json:"version,omitempty"`
+}
+
+// DepGraphInventory produces a flat list of the packages in a dependency
+// graph, sorted by name.  Each parent in the graph must correspond to one of
+// the specified resolved packages; other packages are ignored.  A repo's
+// version is its installed version, or its commit hash if the version cannot
+// be inferred.  The version is left empty if neither can be determined.
+func DepGraphInventory(graph DepGraph,
+	rpkgs []*resolve.ResolvePackage) []DepInventoryEntry {
+
+	repoVers := map[string]string{}
+	repoVer := func(rname string) string {
+		if v, ok := repoVers[rname]; ok {
+			return v
+		}
+
+		v := ""
+		ver, err := project.GetProject().GetRepoVersion(rname)
+		if err == nil && ver != nil {
+			v = ver.String()
+		}
+		repoVers[rname] = v
+		return v
+	}
+
+	entries := []DepInventoryEntry{}
+	for _, rpkg := range rpkgs {
+		lpkg := rpkg.Lpkg
+		if _, ok := graph[lpkg.FullName()]; !ok {
+			continue
+		}
+
+		entries = append(entries, DepInventoryEntry{
+			Name:    lpkg.FullName(),
+			Type:    pkg.PackageTypeNames[lpkg.Type()],
+			Repo:    lpkg.Repo().Name(),
+			Version: repoVer(lpkg.Repo().Name()),
+		})
+	}
+
+	sort.Slice(entries, func(i int, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+
+	return entries
+}
+
+// DepGraphInventoryJson converts the output of DepGraphInventory() to JSON.
+func DepGraphInventoryJson(graph DepGraph,
+	rpkgs []*resolve.ResolvePackage) (string, error) {
+
+	j, err := json.MarshalIndent(DepGraphInventory(graph, rpkgs), "", "    ")
+	if err != nil {
+		return "", util.ChildNewtError(err)
+	}
+
+	return string(j) + "\n", nil
+}
+
+func renderGraph(graph DepGraph, format string, w io.Writer,
+	textFn func(DepGraph) string, vizFn func(DepGraph) string) error {
+
+	var s string
+	switch format {
+	case DEP_GRAPH_FORMAT_TEXT:
+		s = textFn(graph) + "\n"
+	case DEP_GRAPH_FORMAT_DOT:
+		s = vizFn(graph)
+	case DEP_GRAPH_FORMAT_JSON:
+		var err error
+		if s, err = depGraphJson(graph); err != nil {
+			return err
+		}
+	default:
+		return util.FmtNewtError(
+			"Invalid graph format: %s; must be one of: %s",
+			format, strings.Join(DepGraphFormats, ", "))
+	}
+
+	if _, err := io.WriteString(w, s); err != nil {
+		return util.ChildNewtError(err)
+	}
+
+	return nil
+}
+
+// DepGraphRender writes a dependency graph to `w` in the specified format
+// (one of DepGraphFormats).
+func DepGraphRender(dg DepGraph, format string, w io.Writer) error {
+	return renderGraph(dg, format, w, DepGraphText, DepGraphViz)
+}
+
+// RevdepGraphRender writes a reverse-dependency graph to `w` in the specified
+// format (one of DepGraphFormats).
+func RevdepGraphRender(rdg DepGraph, format string, w io.Writer) error {
+	return renderGraph(rdg, format, w, RevdepGraphText, RevdepGraphViz)
+}
+
+// Produces a single DOT graph containing the edges of both a dependency graph
+// and a reverse-dependency graph.  Forward-dependency edges are drawn solid;
+// reverse-dependency edges are drawn dashed.  A reverse edge that duplicates a
+// forward edge is omitted.
+func CombinedGraphViz(dg DepGraph, rdg DepGraph) string {
+	type edge struct {
+		from string
+		to   string
+	}
+	seen := map[edge]struct{}{}
+
+	buffer := bytes.NewBufferString("")
+
+	fmt.Fprintf(buffer, "digraph combined {\n")
+
+	parents := make([]string, 0, len(dg))
+	for pname, _ := range dg {
+		parents = append(parents, pname)
+	}
+	sort.Strings(parents)
+
+	for _, pname := range parents {
+		for _, child := range dg[pname] {
+			seen[edge{pname, child.PkgName}] = struct{}{}
+
+			depStr := strings.TrimPrefix(depString(child), child.PkgName)
+			fmt.Fprintf(buffer,
+				"  \"%s\" -> \"%s\" [label=\"%s\", style=solid];\n",
+				pname, child.PkgName, depStr)
+		}
+	}
+
+	parents = make([]string, 0, len(rdg))
+	for pname, _ := range rdg {
+		parents = append(parents, pname)
+	}
+	sort.Strings(parents)
+
+	for _, pname := range parents {
+		for _, child := range rdg[pname] {
+			if _, ok := seen[edge{child.PkgName, pname}]; ok {
+				continue
+			}
+
+			depStr := strings.TrimPrefix(depString(child), child.PkgName)
+			fmt.Fprintf(buffer,
+				"  \"%s\" -> \"%s\" [label=\"%s\", style=dashed];\n",
+				child.PkgName, pname, depStr)
+		}
+	}
+	fmt.Fprintf(buffer, "}\n")
+
+	return buffer.String()
+}
+
 // Extracts a new dependency graph containing only the specified parents.
 //
 // @param dg                    The source graph to filter.
@@ -274,3 +551,421 @@ func FilterDepGraph(dg DepGraph, parents []*resolve.ResolvePackage) (
 
 	return newDg, missing
 }
+
+// Extracts the subgraph reachable from the specified package.
+//
+// @param dg                    The source graph to prune.
+// @param root                  The full name of the package to root the
+//                                  subgraph at.
+// @param depth                 The maximum number of edges to follow from the
+//                                  root; <= 0 means no limit.
+//
+// @return DepGraph             Graph containing the root and its transitive
+//                                  children.
+func SubtreeDepGraph(dg DepGraph, root string, depth int) DepGraph {
+	newDg := DepGraph{}
+
+	if _, ok := dg[root]; !ok {
+		return newDg
+	}
+
+	// Breadth-first traversal so that each package is recorded at its
+	// minimum distance from the root.
+	dists := map[string]int{root: 0}
+	queue := []string{root}
+	for len(queue) > 0 {
+		pname := queue[0]
+		queue = queue[1:]
+
+		if depth > 0 && dists[pname] >= depth {
+			continue
+		}
+
+		newDg[pname] = dg[pname]
+		for _, child := range dg[pname] {
+			if _, ok := dists[child.PkgName]; !ok {
+				dists[child.PkgName] = dists[pname] + 1
+				queue = append(queue, child.PkgName)
+			}
+		}
+	}
+
+	return newDg
+}
+
+// Extracts the neighborhood of the specified package: the packages it depends
+// on and the packages that depend on it, directly or indirectly.  Only edges
+// between packages in the neighborhood are retained.
+//
+// @param dg                    The source graph to prune.
+// @param focus                 The full name of the package to center the
+//                                  subgraph on.
+//
+// @return DepGraph             Graph containing the package, its ancestors,
+//                                  and its descendants.
+func FocusDepGraph(dg DepGraph, focus string) DepGraph {
+	newDg := DepGraph{}
+
+	if _, ok := dg[focus]; !ok {
+		return newDg
+	}
+
+	keep := map[string]struct{}{}
+	for pname, _ := range SubtreeDepGraph(dg, focus, 0) {
+		keep[pname] = struct{}{}
+	}
+
+	// Walk the graph backwards from the focus package to find its ancestors.
+	parents := map[string][]string{}
+	for pname, children := range dg {
+		for _, child := range children {
+			parents[child.PkgName] = append(parents[child.PkgName], pname)
+		}
+	}
+	queue := []string{focus}
+	for len(queue) > 0 {
+		pname := queue[0]
+		queue = queue[1:]
+
+		for _, parent := range parents[pname] {
+			if _, ok := keep[parent]; !ok {
+				keep[parent] = struct{}{}
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	for pname, _ := range keep {
+		children := []DepEntry{}
+		for _, child := range dg[pname] {
+			if _, ok := keep[child.PkgName]; ok {
+				children = append(children, child)
+			}
+		}
+		newDg[pname] = children
+	}
+
+	return newDg
+}
+
+// Removes packages matching any of the specified glob patterns from a
+// dependency graph.  Edges are reconnected around removed packages: a parent
+// of a removed package inherits the removed package's children.  Reconnected
+// edges carry no dependency expressions, as they represent indirect
+// dependencies.
+//
+// @param dg                    The source graph.
+// @param patterns              Glob patterns (path.Match syntax) of packages
+//                                  to remove.
+//
+// @return DepGraph             Graph without the matching packages.
+//         []string             Patterns that did not match any package.
+//         error                Error if a pattern is malformed.
+func ExcludeDepGraph(dg DepGraph, patterns []string) (
+	DepGraph, []string, error) {
+
+	matched := make([]bool, len(patterns))
+	excluded := map[string]bool{}
+
+	check := func(pname string) error {
+		if _, ok := excluded[pname]; ok {
+			return nil
+		}
+
+		excluded[pname] = false
+		for i, pattern := range patterns {
+			m, err := path.Match(pattern, pname)
+			if err != nil {
+				return util.FmtNewtError("Invalid exclude pattern \"%s\": %s",
+					pattern, err.Error())
+			}
+			if m {
+				matched[i] = true
+				excluded[pname] = true
+			}
+		}
+
+		return nil
+	}
+
+	for pname, children := range dg {
+		if err := check(pname); err != nil {
+			return nil, nil, err
+		}
+		for _, child := range children {
+			if err := check(child.PkgName); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	// Collects the children of `pname`, replacing each excluded child with
+	// its own children.
+	var collect func(pname string, visited map[string]bool,
+		entries map[string]DepEntry, direct bool)
+	collect = func(pname string, visited map[string]bool,
+		entries map[string]DepEntry, direct bool) {
+
+		for _, child := range dg[pname] {
+			if !excluded[child.PkgName] {
+				if _, ok := entries[child.PkgName]; !ok || direct {
+					if direct {
+						entries[child.PkgName] = child
+					} else {
+						entries[child.PkgName] = DepEntry{
+							PkgName: child.PkgName,
+						}
+					}
+				}
+			} else if !visited[child.PkgName] {
+				visited[child.PkgName] = true
+				collect(child.PkgName, visited, entries, false)
+			}
+		}
+	}
+
+	newDg := DepGraph{}
+	for pname, _ := range dg {
+		if excluded[pname] {
+			continue
+		}
+
+		entries := map[string]DepEntry{}
+		collect(pname, map[string]bool{pname: true}, entries, true)
+
+		newDg[pname] = make([]DepEntry, 0, len(entries))
+		for _, entry := range entries {
+			newDg[pname] = append(newDg[pname], entry)
+		}
+		SortDepEntries(newDg[pname])
+	}
+
+	unmatched := []string{}
+	for i, pattern := range patterns {
+		if !matched[i] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	return newDg, unmatched, nil
+}
+
+// Builds a dependency graph containing every package in the project.  Only
+// unconditional dependencies are included; conditional dependencies cannot be
+// evaluated without a target's syscfg settings.  Optional dependencies are
+// excluded as well, since they never pull a package into a build.
+// Dependencies that do not resolve to a package are ignored.
+//
+// @return DepGraph             Project-wide dependency graph.
+func ProjectDepGraph() DepGraph {
+	proj := project.GetProject()
+	graph := DepGraph{}
+
+	for _, pkgMap := range proj.PackageList() {
+		for _, pkgItf := range *pkgMap {
+			lpkg, ok := pkgItf.(*pkg.LocalPackage)
+			if !ok {
+				continue
+			}
+
+			pname := lpkg.FullName()
+			graph[pname] = []DepEntry{}
+
+			depNames, err := lpkg.PkgY.GetValStringSlice("pkg.deps", nil)
+			if err != nil {
+				log.Debugf("Error reading dependencies of %s: %s",
+					pname, err.Error())
+			}
+
+			seen := map[string]struct{}{}
+			for _, depName := range depNames {
+				dep, err := pkg.NewDependency(lpkg.Repo(), depName)
+				if err != nil {
+					log.Debugf("Invalid dependency in %s: %s",
+						pname, err.Error())
+					continue
+				}
+				if dep.Optional {
+					continue
+				}
+
+				depPkg := proj.ResolveDependency(dep)
+				if depPkg == nil {
+					log.Debugf("Unresolved dependency in %s: %s",
+						pname, depName)
+					continue
+				}
+
+				cname := depPkg.FullName()
+				if _, ok := seen[cname]; ok {
+					continue
+				}
+				seen[cname] = struct{}{}
+
+				graph[pname] = append(graph[pname], DepEntry{PkgName: cname})
+			}
+
+			SortDepEntries(graph[pname])
+		}
+	}
+
+	return graph
+}
+
+// Determines the packages in a dependency graph that no other package depends
+// on.  If some packages can only be reached through a dependency cycle, the
+// first such package in name order is added as well, so that every package in
+// the graph is reachable from one of the returned roots.
+//
+// @param graph                 The dependency graph to search.
+//
+// @return []string             Sorted full names of the root packages,
+//                                  followed by any roots added to break
+//                                  cycles.
+func DepGraphRoots(graph DepGraph) []string {
+	names := make([]string, 0, len(graph))
+	hasParent := map[string]struct{}{}
+	for pname, children := range graph {
+		names = append(names, pname)
+		for _, child := range children {
+			if child.PkgName != pname {
+				hasParent[child.PkgName] = struct{}{}
+			}
+		}
+	}
+	sort.Strings(names)
+
+	reached := map[string]struct{}{}
+	var reach func(pname string)
+	reach = func(pname string) {
+		if _, ok := reached[pname]; ok {
+			return
+		}
+		reached[pname] = struct{}{}
+		for _, child := range graph[pname] {
+			reach(child.PkgName)
+		}
+	}
+
+	roots := []string{}
+	for _, pname := range names {
+		if _, ok := hasParent[pname]; !ok {
+			roots = append(roots, pname)
+			reach(pname)
+		}
+	}
+
+	for _, pname := range names {
+		if _, ok := reached[pname]; !ok {
+			roots = append(roots, pname)
+			reach(pname)
+		}
+	}
+
+	return roots
+}
+
+func pkgDepth(graph DepGraph, pname string, depths map[string]int,
+	visiting map[string]struct{}) int {
+
+	if depth, ok := depths[pname]; ok {
+		return depth
+	}
+
+	// A package that is already on the stack indicates a cycle.  Break it by
+	// not following the edge back into the package.
+	if _, ok := visiting[pname]; ok {
+		return -1
+	}
+	visiting[pname] = struct{}{}
+
+	depth := 0
+	for _, child := range graph[pname] {
+		if d := pkgDepth(graph, child.PkgName, depths, visiting) + 1; d > depth {
+			depth = d
+		}
+	}
+
+	delete(visiting, pname)
+	depths[pname] = depth
+
+	return depth
+}
+
+// Calculates the depth of each package in a dependency graph.  A package's
+// depth is its maximum distance from a leaf (a package with no
+// dependencies); leaves have a depth of 0.  Edges that would complete a
+// dependency cycle are not followed.  Packages are visited in name order so
+// that the depths within a cycle do not vary from run to run.
+//
+// @param graph                 The dependency graph to measure.
+//
+// @return map[string]int       Package full name to depth.
+func DepGraphDepths(graph DepGraph) map[string]int {
+	depths := map[string]int{}
+	visiting := map[string]struct{}{}
+
+	names := make([]string, 0, len(graph))
+	for pname, _ := range graph {
+		names = append(names, pname)
+	}
+	sort.Strings(names)
+
+	for _, pname := range names {
+		pkgDepth(graph, pname, depths, visiting)
+	}
+
+	return depths
+}
+
+// Finds the longest dependency chain in a dependency graph, measured by
+// package count.  Each package in the chain depends on the next.  If several
+// chains have the same length, the first one in name order is returned.
+// Edges that would complete a dependency cycle are not followed.
+//
+// @param graph                 The dependency graph to search.
+//
+// @return []string             Full names of the packages in the chain, from
+//                                  depender to final dependee.
+func DepGraphLongestPath(graph DepGraph) []string {
+	depths := DepGraphDepths(graph)
+
+	names := make([]string, 0, len(graph))
+	for pname, _ := range graph {
+		names = append(names, pname)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	cur := names[0]
+	for _, pname := range names[1:] {
+		if depths[pname] > depths[cur] {
+			cur = pname
+		}
+	}
+
+	path := []string{cur}
+	for depths[cur] > 0 {
+		children := append([]DepEntry(nil), graph[cur]...)
+		SortDepEntries(children)
+
+		next := ""
+		for _, child := range children {
+			if depths[child.PkgName] == depths[cur]-1 {
+				next = child.PkgName
+				break
+			}
+		}
+		if next == "" {
+			break
+		}
+
+		path = append(path, next)
+		cur = next
+	}
+
+	return path
+}
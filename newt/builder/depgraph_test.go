@@ -0,0 +1,134 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package builder
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/parse"
+	"mynewt.apache.org/newt/newt/testutil"
+)
+
+func testDepGraph(t *testing.T) DepGraph {
+	expr, err := parse.LexAndParse("A_BOOL")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return DepGraph{
+		"apps/blinky": []DepEntry{
+			{PkgName: "libs/a"},
+			{
+				PkgName:  "libs/b",
+				DepExprs: parse.NewExprSet([]*parse.Node{expr}),
+			},
+		},
+		"libs/a": []DepEntry{},
+		"libs/b": []DepEntry{
+			{PkgName: "libs/a"},
+		},
+	}
+}
+
+func TestDepGraphRender(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: DEP_GRAPH_FORMAT_TEXT,
+			want: `Dependency graph (depender --> [dependees]):
+    * apps/blinky --> [libs/a libs/b(syscfg:A_BOOL)]
+    * libs/a --> []
+    * libs/b --> [libs/a]
+`,
+		},
+		{
+			format: DEP_GRAPH_FORMAT_DOT,
+			want: `digraph deps {
+  "apps/blinky" -> "libs/a" [label=""];
+  "apps/blinky" -> "libs/b" [label="(syscfg:A_BOOL)"];
+  "libs/b" -> "libs/a" [label=""];
+}
+`,
+		},
+		{
+			format: DEP_GRAPH_FORMAT_JSON,
+			want: `{
+    "apps/blinky": [
+        {
+            "name": "libs/a"
+        },
+        {
+            "name": "libs/b",
+            "condition": "(syscfg:A_BOOL)"
+        }
+    ],
+    "libs/a": [],
+    "libs/b": [
+        {
+            "name": "libs/a"
+        }
+    ]
+}
+`,
+		},
+	}
+
+	dg := testDepGraph(t)
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := DepGraphRender(dg, test.format, &buf); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.format, err.Error())
+			continue
+		}
+
+		if got := buf.String(); got != test.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", test.format, got, test.want)
+		}
+	}
+}
+
+func TestDepGraphRenderInvalidFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if err := DepGraphRender(testDepGraph(t), "svg", &buf); err == nil {
+		t.Errorf("expected error for invalid format")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("unexpected output for invalid format: %s", buf.String())
+	}
+}
+
+func TestProjectDepGraphOptional(t *testing.T) {
+	_, cleanup := testutil.NewProject(t, map[string]string{
+		"libs/c/pkg.yml": "pkg.name: libs/c\npkg.type: lib\n" +
+			"pkg.deps:\n    - libs/a\n    - libs/b?\n    - libs/nothere?\n",
+	})
+	defer cleanup()
+
+	dg := ProjectDepGraph()
+
+	want := []DepEntry{{PkgName: "libs/a"}}
+	if got := dg["libs/c"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("libs/c dependencies = %v; want %v", got, want)
+	}
+}
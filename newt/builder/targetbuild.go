@@ -259,6 +259,24 @@ func (t *TargetBuilder) Resolve() (*resolve.Resolution, error) {
 	return t.res, nil
 }
 
+// DependencyClosure returns every package the target pulls in: the union of
+// the loader and app dependencies, including the seed packages themselves.
+// The returned slice is sorted by package name.
+func (t *TargetBuilder) DependencyClosure() ([]*resolve.ResolvePackage, error) {
+	if err := t.ensureResolved(); err != nil {
+		return nil, err
+	}
+
+	rpkgs := make([]*resolve.ResolvePackage, len(t.res.MasterSet.Rpkgs))
+	copy(rpkgs, t.res.MasterSet.Rpkgs)
+
+	sort.Slice(rpkgs, func(i int, j int) bool {
+		return rpkgs[i].Lpkg.FullName() < rpkgs[j].Lpkg.FullName()
+	})
+
+	return rpkgs, nil
+}
+
 func (t *TargetBuilder) validateAndWriteCfg() error {
 	if err := t.ensureResolved(); err != nil {
 		return err
@@ -281,6 +299,10 @@ func (t *TargetBuilder) validateAndWriteCfg() error {
 		log.Warn(line)
 	}
 
+	for _, line := range t.res.StageConflictWarning() {
+		log.Warn(line)
+	}
+
 	incDir := GeneratedIncludeDir(t.target.FullName())
 	srcDir := GeneratedSrcDir(t.target.FullName())
 
@@ -0,0 +1,157 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package builder
+
+import (
+	"reflect"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/target"
+	"mynewt.apache.org/newt/newt/testutil"
+)
+
+// Resolves the specified target in the current project and returns the full
+// names of the packages in its dependency closure.
+func closureNames(t *testing.T, targetName string) []string {
+	tgt := target.GetTargets()[targetName]
+	if tgt == nil {
+		t.Fatalf("target %s not found", targetName)
+	}
+
+	b, err := NewTargetBuilder(tgt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rpkgs, err := b.DependencyClosure()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := make([]string, len(rpkgs))
+	for i, rpkg := range rpkgs {
+		names[i] = rpkg.Lpkg.FullName()
+	}
+
+	return names
+}
+
+func TestDependencyClosure(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra map[string]string
+		want  []string
+	}{
+		{
+			name:  "base",
+			extra: nil,
+			want: []string{
+				"apps/blinky",
+				"compiler/sim",
+				"hw/bsp/mybsp",
+				"libs/a",
+				"libs/b",
+				"targets/foo",
+			},
+		},
+		{
+			// An unused package is not part of the closure.
+			name: "unused",
+			extra: map[string]string{
+				"libs/c/pkg.yml": "pkg.name: libs/c\npkg.type: lib\n",
+			},
+			want: []string{
+				"apps/blinky",
+				"compiler/sim",
+				"hw/bsp/mybsp",
+				"libs/a",
+				"libs/b",
+				"targets/foo",
+			},
+		},
+		{
+			// Indirect dependencies are included; the result is sorted.
+			name: "indirect",
+			extra: map[string]string{
+				"libs/b/pkg.yml": "pkg.name: libs/b\npkg.type: lib\n" +
+					"pkg.deps:\n    - libs/c\n",
+				"libs/c/pkg.yml": "pkg.name: libs/c\npkg.type: lib\n",
+			},
+			want: []string{
+				"apps/blinky",
+				"compiler/sim",
+				"hw/bsp/mybsp",
+				"libs/a",
+				"libs/b",
+				"libs/c",
+				"targets/foo",
+			},
+		},
+		{
+			// Optional dependencies don't pull packages in, and
+			// unresolvable ones are ignored.
+			name: "optional",
+			extra: map[string]string{
+				"libs/b/pkg.yml": "pkg.name: libs/b\npkg.type: lib\n" +
+					"pkg.deps:\n    - libs/c?\n    - libs/nothere?\n" +
+					"    - \"@norepo/libs/x?\"\n",
+				"libs/c/pkg.yml": "pkg.name: libs/c\npkg.type: lib\n",
+			},
+			want: []string{
+				"apps/blinky",
+				"compiler/sim",
+				"hw/bsp/mybsp",
+				"libs/a",
+				"libs/b",
+				"targets/foo",
+			},
+		},
+		{
+			// An optional dependency is used if another package requires
+			// the dependee.
+			name: "optional required",
+			extra: map[string]string{
+				"libs/a/pkg.yml": "pkg.name: libs/a\npkg.type: lib\n" +
+					"pkg.deps:\n    - libs/b?\n    - libs/c\n" +
+					"pkg.init:\n    a_init: 100\n",
+				"libs/c/pkg.yml": "pkg.name: libs/c\npkg.type: lib\n",
+			},
+			want: []string{
+				"apps/blinky",
+				"compiler/sim",
+				"hw/bsp/mybsp",
+				"libs/a",
+				"libs/b",
+				"libs/c",
+				"targets/foo",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		_, cleanup := testutil.NewProject(t, test.extra)
+		got := closureNames(t, "targets/foo")
+		cleanup()
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: closure = %v; want %v", test.name, got, test.want)
+		}
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"sort"
 	"strings"
 
+	"mynewt.apache.org/newt/newt/interfaces"
 	"mynewt.apache.org/newt/newt/pkg"
 	"mynewt.apache.org/newt/newt/project"
 
@@ -37,6 +38,13 @@ type TabCompleteFn func() []string
 
 var tabCompleteEntries = map[*cobra.Command]TabCompleteFn{}
 
+// ArgCompleteFn completes a command argument.  `prevArgs` contains the
+// arguments preceding the one being completed; `partial` is the partially
+// typed argument.  Candidates that don't begin with `partial` are discarded.
+type ArgCompleteFn func(prevArgs []string, partial string) []string
+
+var argCompleteEntries = map[*cobra.Command]ArgCompleteFn{}
+
 func AddTabCompleteFn(cmd *cobra.Command, cb TabCompleteFn) {
 	if cmd.ValidArgs != nil || tabCompleteEntries[cmd] != nil {
 		panic("tab completion values generated twice for command " +
@@ -46,6 +54,17 @@ func AddTabCompleteFn(cmd *cobra.Command, cb TabCompleteFn) {
 	tabCompleteEntries[cmd] = cb
 }
 
+// AddArgCompleteFn registers a function that completes any of a command's
+// arguments, not just the first.
+func AddArgCompleteFn(cmd *cobra.Command, cb ArgCompleteFn) {
+	if argCompleteEntries[cmd] != nil {
+		panic("argument completion registered twice for command " +
+			cmd.Name())
+	}
+
+	argCompleteEntries[cmd] = cb
+}
+
 func GenerateTabCompleteValues() {
 	for cmd, cb := range tabCompleteEntries {
 		cmd.ValidArgs = cb()
@@ -118,6 +137,44 @@ func mfgList() []string {
 	return targetNames
 }
 
+// Completes the arguments of `newt target set`: a target name, followed by
+// variable names.  The values of package variables (app, bsp, loader) are
+// completed with the names of packages of the appropriate type.
+func targetSetComplete(prevArgs []string, partial string) []string {
+	if len(prevArgs) == 0 {
+		return targetList()
+	}
+
+	eq := strings.Index(partial, "=")
+	if eq == -1 {
+		names := make([]string, len(setVars))
+		for i, v := range setVars {
+			names[i] = v + "="
+		}
+		return names
+	}
+
+	var pkgType interfaces.PackageType
+	varName := partial[:eq]
+	switch varName {
+	case "app", "loader":
+		pkgType = pkg.PACKAGE_TYPE_APP
+	case "bsp":
+		pkgType = pkg.PACKAGE_TYPE_BSP
+	default:
+		return nil
+	}
+
+	names := pkgNameList(func(pack *pkg.LocalPackage) bool {
+		return pack.Type() == pkgType
+	})
+	for i, name := range names {
+		names[i] = varName + "=" + name
+	}
+
+	return names
+}
+
 func completeRunCmd(cmd *cobra.Command, args []string) {
 	cmd_line := os.Getenv("COMP_LINE")
 
@@ -221,6 +278,31 @@ func completeRunCmd(cmd *cobra.Command, args []string) {
 		})
 	}
 
+	/* let the command complete any of its arguments if it knows how.  Bash
+	 * treats '=' as a word break, so only the text following a '=' in the
+	 * argument being completed is printed. */
+	if cb := argCompleteEntries[found_cmd]; cb != nil {
+		words := strings.Split(extra_str, " ")
+		partial := words[len(words)-1]
+
+		prevArgs := []string{}
+		for _, w := range words[:len(words)-1] {
+			if w != "" && !strings.HasPrefix(w, "-") {
+				prevArgs = append(prevArgs, w)
+			}
+		}
+
+		for _, c := range cb(prevArgs, partial) {
+			if strings.HasPrefix(c, partial) {
+				if eq := strings.Index(partial, "="); eq != -1 {
+					c = c[eq+1:]
+				}
+				fmt.Printf("%s\n", c)
+			}
+		}
+		return
+	}
+
 	/* dump out valid arguments */
 	for _, c := range found_cmd.ValidArgs {
 		if strings.HasPrefix(c, extra_str) {
@@ -20,21 +20,33 @@
 package cli
 
 import (
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
+	"mynewt.apache.org/newt/newt/builder"
 	"mynewt.apache.org/newt/newt/interfaces"
 	"mynewt.apache.org/newt/newt/newtutil"
 	"mynewt.apache.org/newt/newt/pkg"
 	"mynewt.apache.org/newt/newt/project"
+	"mynewt.apache.org/newt/newt/repo"
+	"mynewt.apache.org/newt/newt/syscfg"
 	"mynewt.apache.org/newt/util"
 )
 
 var NewTypeStr = "pkg"
+var pkgDepthSort string
+var pkgValidateJson bool
+var pkgValidateIncludes bool
+var pkgScanJson bool
+var pkgCreateType string = "lib"
 
 func pkgNewCmd(cmd *cobra.Command, args []string) {
 
@@ -57,6 +69,61 @@ func pkgNewCmd(cmd *cobra.Command, args []string) {
 	}
 }
 
+func pkgCreateCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		NewtUsage(cmd, util.NewNewtError("Exactly one argument required"))
+	}
+
+	proj := TryGetProject()
+
+	pkgType, err := pkg.PackageTypeFromString(pkgCreateType)
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+	if pkgType == pkg.PACKAGE_TYPE_TARGET {
+		NewtUsage(cmd, util.NewNewtError(
+			"Use \"newt target create\" to create a target"))
+	}
+
+	repoName, pkgName, err := newtutil.ParsePackageString(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+	if repoName != "" {
+		NewtUsage(cmd, util.NewNewtError("Package name cannot contain "+
+			"repo; must be local"))
+	}
+
+	if !newtutil.NewtForce {
+		if err := checkNewPackageCollision(pkgName); err != nil {
+			NewtUsage(nil, err)
+		}
+	}
+
+	repo := proj.LocalRepo()
+	pack := pkg.NewLocalPackage(repo, repo.Path()+"/"+pkgName)
+	pack.SetName(pkgName)
+	pack.SetType(pkgType)
+
+	if err := pack.Save(); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	// Create the conventional source and header directories.
+	dirs := []string{
+		pack.BasePath() + "/src",
+		pack.BasePath() + "/include/" + path.Base(pkgName),
+	}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			NewtUsage(nil, util.ChildNewtError(err))
+		}
+	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		"Package %s successfully created\n", pkgName)
+}
+
 type dirOperation func(string, string) error
 
 func pkgCopyCmd(cmd *cobra.Command, args []string) {
@@ -211,6 +278,469 @@ func pkgRemoveCmd(cmd *cobra.Command, args []string) {
 	}
 }
 
+func pkgDepthCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		NewtUsage(cmd, util.NewNewtError("Command takes no arguments"))
+	}
+
+	if pkgDepthSort != "depth" && pkgDepthSort != "name" {
+		NewtUsage(cmd, util.FmtNewtError(
+			"Invalid sort key \"%s\"; must be \"depth\" or \"name\"",
+			pkgDepthSort))
+	}
+
+	proj := TryGetProject()
+	interfaces.SetProject(proj)
+
+	depths := builder.DepGraphDepths(builder.ProjectDepGraph())
+
+	names := make([]string, 0, len(depths))
+	for name, _ := range depths {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i int, j int) bool {
+		if pkgDepthSort == "depth" && depths[names[i]] != depths[names[j]] {
+			return depths[names[i]] > depths[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	for _, name := range names {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%5d %s\n",
+			depths[name], name)
+	}
+}
+
+func pkgTreeCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		NewtUsage(cmd, util.NewNewtError("Command takes no arguments"))
+	}
+
+	proj := TryGetProject()
+	interfaces.SetProject(proj)
+
+	dg := builder.ProjectDepGraph()
+	if len(dg) == 0 {
+		return
+	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n",
+		builder.DepGraphTree(dg, builder.DepGraphRoots(dg)))
+}
+
+func pkgVerifyCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		NewtUsage(cmd, util.NewNewtError("Command takes no arguments"))
+	}
+
+	proj := TryGetProject()
+	interfaces.SetProject(proj)
+
+	dangling := proj.DanglingLinks()
+	if len(dangling) == 0 {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "No problems found\n")
+		return
+	}
+
+	NewtUsage(nil, util.FmtNewtError("Dangling transient package links "+
+		"detected:\n    %s", strings.Join(dangling, "\n    ")))
+}
+
+// Validates the package in the specified directory without loading it.  If the
+// package failed to load for a reason the validation doesn't detect (e.g., a
+// malformed pkg.yml file), `loadErr` is reported as an error so that the
+// failure is not lost.
+func pkgValidateDir(r *repo.Repo, dir string,
+	loadErr string) []pkg.PackageProblem {
+
+	problems, err := pkg.ValidateDir(r, dir, pkgValidateIncludes)
+	if err != nil {
+		return []pkg.PackageProblem{{
+			Severity: pkg.PROBLEM_SEVERITY_ERROR,
+			File:     filepath.Join(dir, pkg.PACKAGE_FILE_NAME),
+			Message:  err.Error(),
+		}}
+	}
+
+	if loadErr != "" {
+		for _, p := range problems {
+			if p.Severity == pkg.PROBLEM_SEVERITY_ERROR {
+				return problems
+			}
+		}
+		problems = append(problems, pkg.PackageProblem{
+			Severity: pkg.PROBLEM_SEVERITY_ERROR,
+			File:     filepath.Join(dir, pkg.PACKAGE_FILE_NAME),
+			Message:  loadErr,
+		})
+	}
+
+	return problems
+}
+
+// Collects the problems of the packages specified on the command line.  Each
+// argument is either a package name or a package directory; a directory is
+// checked even if its package failed to load.  If no arguments are specified,
+// every package in the project is checked, including the packages that failed
+// to load.
+func pkgValidateProblems(args []string) []pkg.PackageProblem {
+	validate := func(lpkg *pkg.LocalPackage) []pkg.PackageProblem {
+		problems := lpkg.Validate()
+		if pkgValidateIncludes {
+			problems = append(problems, lpkg.ValidateIncludes()...)
+		}
+		return problems
+	}
+
+	proj := TryGetProject()
+
+	problems := []pkg.PackageProblem{}
+
+	if len(args) == 0 {
+		for _, pkgList := range proj.PackageList() {
+			for _, pack := range *pkgList {
+				lpkg := pack.(*pkg.LocalPackage)
+				problems = append(problems, validate(lpkg)...)
+			}
+		}
+
+		// Packages that failed to load are not in the package list; check
+		// their directories instead.
+		for _, w := range proj.ScanWarnings() {
+			if w.Category != pkg.SCAN_WARNING_LOAD_ERROR {
+				continue
+			}
+
+			r := proj.FindRepo(w.Repo)
+			if r == nil {
+				continue
+			}
+
+			dir := filepath.Join(r.Path(), w.Path)
+			problems = append(problems, pkgValidateDir(r, dir, w.Message)...)
+		}
+	}
+
+	for _, arg := range args {
+		if util.NodeExist(path.Join(arg, pkg.PACKAGE_FILE_NAME)) {
+			dir, err := filepath.Abs(arg)
+			if err != nil {
+				NewtUsage(nil, util.ChildNewtError(err))
+			}
+
+			problems = append(problems,
+				pkgValidateDir(proj.LocalRepo(), dir, "")...)
+			continue
+		}
+
+		lpkgs, err := ResolvePackages([]string{arg})
+		if err != nil {
+			NewtUsage(nil, err)
+		}
+		problems = append(problems, validate(lpkgs[0])...)
+	}
+
+	sort.SliceStable(problems, func(i int, j int) bool {
+		return problems[i].File < problems[j].File
+	})
+
+	return problems
+}
+
+func pkgValidateCmd(cmd *cobra.Command, args []string) {
+	problems := pkgValidateProblems(args)
+
+	numErrors := 0
+	for _, p := range problems {
+		if p.Severity == pkg.PROBLEM_SEVERITY_ERROR {
+			numErrors++
+		}
+	}
+
+	if pkgValidateJson {
+		j, err := json.MarshalIndent(problems, "", "    ")
+		if err != nil {
+			NewtUsage(nil, util.ChildNewtError(err))
+		}
+		fmt.Println(string(j))
+	} else if len(problems) == 0 {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "No problems found\n")
+	} else {
+		for _, p := range problems {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", p.String())
+		}
+	}
+
+	if numErrors > 0 {
+		NewtUsage(nil, util.FmtNewtError("%d package error(s) found",
+			numErrors))
+	}
+}
+
+// Indicates whether a package matches a search term.  A package matches if
+// one of its keywords or its description contains the term (case
+// insensitive).  `term` must be lowercase.
+func pkgMatchesTerm(lpkg *pkg.LocalPackage, term string) bool {
+	for _, kw := range lpkg.Desc().Keywords {
+		if strings.Contains(strings.ToLower(kw), term) {
+			return true
+		}
+	}
+
+	return strings.Contains(strings.ToLower(lpkg.Desc().Description), term)
+}
+
+func pkgScanCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		NewtUsage(cmd, util.NewNewtError("Command takes no arguments"))
+	}
+
+	// Don't use TryGetProject(); it displays the warnings being reported.
+	proj, err := project.TryGetProject()
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	warnings := append([]pkg.ScanWarning{}, proj.ScanWarnings()...)
+	sort.SliceStable(warnings, func(i int, j int) bool {
+		if warnings[i].Repo != warnings[j].Repo {
+			return warnings[i].Repo < warnings[j].Repo
+		}
+		return warnings[i].Path < warnings[j].Path
+	})
+
+	if pkgScanJson {
+		j, err := json.MarshalIndent(warnings, "", "    ")
+		if err != nil {
+			NewtUsage(nil, util.ChildNewtError(err))
+		}
+		fmt.Println(string(j))
+		return
+	}
+
+	if len(warnings) == 0 {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "No problems found\n")
+		return
+	}
+
+	for _, w := range warnings {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s: %s\n",
+			w.Category, w.Message)
+	}
+}
+
+func pkgSearchCmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		NewtUsage(cmd, util.NewNewtError("Must specify at least one keyword"))
+	}
+
+	proj := TryGetProject()
+
+	terms := make([]string, len(args))
+	for i, arg := range args {
+		terms[i] = strings.ToLower(arg)
+	}
+
+	// A package must match every term.
+	matches := []*pkg.LocalPackage{}
+	for _, pkgList := range proj.PackageList() {
+		for _, pack := range *pkgList {
+			lpkg := pack.(*pkg.LocalPackage)
+
+			match := true
+			for _, term := range terms {
+				if !pkgMatchesTerm(lpkg, term) {
+					match = false
+					break
+				}
+			}
+			if match {
+				matches = append(matches, lpkg)
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i int, j int) bool {
+		return matches[i].FullName() < matches[j].FullName()
+	})
+
+	for _, lpkg := range matches {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", lpkg.FullName())
+		if lpkg.Desc().Description != "" {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "    %s\n",
+				lpkg.Desc().Description)
+		}
+	}
+}
+
+func pkgSyscfgShadowCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 0 {
+		NewtUsage(cmd, util.NewNewtError("No arguments allowed"))
+	}
+
+	proj := TryGetProject()
+
+	// Collect every definition of each setting across all repos.
+	defMap := map[string][]syscfg.SettingDefault{}
+	for _, pkgList := range proj.PackageList() {
+		for _, pack := range *pkgList {
+			lpkg := pack.(*pkg.LocalPackage)
+			for _, sd := range syscfg.ReadDefaults(lpkg) {
+				defMap[sd.Name] = append(defMap[sd.Name], sd)
+			}
+		}
+	}
+
+	names := []string{}
+	for name, sds := range defMap {
+		for _, sd := range sds[1:] {
+			if sd.Value != sds[0].Value {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"No shadowed syscfg defaults found\n")
+		return
+	}
+
+	for _, name := range names {
+		sds := defMap[name]
+		sort.Slice(sds, func(i int, j int) bool {
+			return sds[i].Lpkg.FullName() < sds[j].Lpkg.FullName()
+		})
+
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", name)
+		for _, sd := range sds {
+			util.StatusMessage(util.VERBOSITY_DEFAULT,
+				"    %s (repo=%s): %s\n",
+				sd.Lpkg.FullName(), sd.Lpkg.Repo().Name(), sd.Value)
+		}
+	}
+}
+
+// Returns the string that `lpkg` should use in its pkg.deps list to refer to
+// `dep`.  A package in another repo is qualified with its repo name.
+func depEntryName(lpkg *pkg.LocalPackage, dep *pkg.LocalPackage) string {
+	if dep.Repo() == lpkg.Repo() {
+		return dep.Name()
+	}
+
+	return "@" + dep.Repo().Name() + "/" + dep.Name()
+}
+
+// Resolves the package whose dependencies are being edited, and each of the
+// specified dependencies.
+func pkgDepsResolveArgs(cmd *cobra.Command, args []string) (
+	*pkg.LocalPackage, []*pkg.LocalPackage) {
+
+	if len(args) < 2 {
+		NewtUsage(cmd, util.NewNewtError(
+			"Must specify a package and at least one dependency"))
+	}
+
+	lpkgs, err := ResolvePackages(args)
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	return lpkgs[0], lpkgs[1:]
+}
+
+func pkgDepsAddCmd(cmd *cobra.Command, args []string) {
+	lpkg, deps := pkgDepsResolveArgs(cmd, args)
+
+	proj := TryGetProject()
+
+	// Determine which packages are already dependencies.  An optional entry
+	// ("x?") counts as a dependency on x.  Key=package, value=optional.
+	depNames, err := lpkg.PkgY.GetValStringSlice("pkg.deps", nil)
+	util.OneTimeWarningError(err)
+
+	existing := map[*pkg.LocalPackage]bool{}
+	for _, depName := range depNames {
+		dep, err := pkg.NewDependency(lpkg.Repo(), depName)
+		if err != nil {
+			continue
+		}
+		if depPkg := proj.ResolveDependency(dep); depPkg != nil {
+			dpkg := depPkg.(*pkg.LocalPackage)
+			if optional, ok := existing[dpkg]; !ok || optional {
+				existing[dpkg] = dep.Optional
+			}
+		}
+	}
+
+	dg := builder.ProjectDepGraph()
+
+	for _, dep := range deps {
+		if dep == lpkg {
+			NewtUsage(nil, util.FmtNewtError(
+				"Package %s cannot depend on itself", lpkg.FullName()))
+		}
+
+		if optional, ok := existing[dep]; ok {
+			if optional {
+				util.StatusMessage(util.VERBOSITY_DEFAULT,
+					"Package %s already optionally depends on %s\n",
+					lpkg.FullName(), dep.FullName())
+			} else {
+				util.StatusMessage(util.VERBOSITY_DEFAULT,
+					"Package %s already depends on %s\n",
+					lpkg.FullName(), dep.FullName())
+			}
+			continue
+		}
+
+		subtree := builder.SubtreeDepGraph(dg, dep.FullName(), 0)
+		if _, ok := subtree[lpkg.FullName()]; ok {
+			util.ErrorMessage(util.VERBOSITY_QUIET,
+				"Warning: %s already depends on %s; this creates a "+
+					"dependency cycle\n", dep.FullName(), lpkg.FullName())
+		}
+
+		if err := lpkg.AddDepEntry(depEntryName(lpkg, dep)); err != nil {
+			NewtUsage(nil, err)
+		}
+		existing[dep] = false
+
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"Added dependency %s to package %s\n",
+			dep.FullName(), lpkg.FullName())
+	}
+}
+
+func pkgDepsRemoveCmd(cmd *cobra.Command, args []string) {
+	lpkg, deps := pkgDepsResolveArgs(cmd, args)
+
+	for _, dep := range deps {
+		names := []string{
+			dep.Name(),
+			dep.FullName(),
+			"@" + dep.Repo().Name() + "/" + dep.Name(),
+		}
+
+		removed, err := lpkg.RemoveDepEntries(names)
+		if err != nil {
+			NewtUsage(nil, err)
+		}
+		if removed == 0 {
+			NewtUsage(nil, util.FmtNewtError(
+				"Package %s does not list %s in pkg.deps",
+				lpkg.FullName(), dep.FullName()))
+		}
+
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"Removed dependency %s from package %s\n",
+			dep.FullName(), lpkg.FullName())
+	}
+}
+
 func AddPackageCommands(cmd *cobra.Command) {
 	/* Add the base package command, on top of which other commands are
 	 * keyed
@@ -285,4 +815,191 @@ func AddPackageCommands(cmd *cobra.Command) {
 	}
 
 	pkgCmd.AddCommand(removeCmd)
+
+	depthCmdHelpText := "Print every package in the project along with its " +
+		"dependency depth.  A package's depth is its maximum distance from " +
+		"a package with no dependencies; such leaf packages have a " +
+		"depth of 0.  The graph is built from each " +
+		"package's unconditional pkg.deps entries, independent of any target."
+	depthCmdHelpEx := "  newt pkg depth\n"
+	depthCmdHelpEx += "  newt pkg depth --sort name"
+
+	depthCmd := &cobra.Command{
+		Use:     "depth",
+		Short:   "Print the dependency depth of each package",
+		Long:    depthCmdHelpText,
+		Example: depthCmdHelpEx,
+		Run:     pkgDepthCmd,
+	}
+
+	depthCmd.PersistentFlags().StringVar(&pkgDepthSort, "sort", "depth",
+		"Sort order: depth (deepest first) or name.")
+
+	pkgCmd.AddCommand(depthCmd)
+
+	treeCmdHelpText := "Print the dependencies of every package in the " +
+		"project as a set of indented trees, one for each package that no " +
+		"other package depends on.  A subtree is only expanded the first " +
+		"time it appears; later appearances are marked with \"(*)\".  The " +
+		"graph is built from each package's unconditional pkg.deps " +
+		"entries, independent of any target."
+	treeCmdHelpEx := "  newt pkg tree"
+
+	treeCmd := &cobra.Command{
+		Use:     "tree",
+		Short:   "Print the project's package dependencies as trees",
+		Long:    treeCmdHelpText,
+		Example: treeCmdHelpEx,
+		Run:     pkgTreeCmd,
+	}
+
+	pkgCmd.AddCommand(treeCmd)
+
+	verifyCmdHelpText := "Check the packages in the project for problems " +
+		"that are not detected when a package is loaded.  Currently, this " +
+		"verifies that every transient package (pkg.link) refers to a " +
+		"package that exists."
+	verifyCmdHelpEx := "  newt pkg verify"
+
+	verifyCmd := &cobra.Command{
+		Use:     "verify",
+		Short:   "Check the project's packages for problems",
+		Long:    verifyCmdHelpText,
+		Example: verifyCmdHelpEx,
+		Run:     pkgVerifyCmd,
+	}
+
+	pkgCmd.AddCommand(verifyCmd)
+
+	validateCmdHelpText := "Check packages for problems in their pkg.yml " +
+		"files.  Each argument is a package name or a package directory.  " +
+		"A directory is checked even if its package cannot be loaded.  If " +
+		"no arguments are specified, every package in the project is " +
+		"checked."
+	validateCmdHelpEx := "  newt pkg validate\n"
+	validateCmdHelpEx += "  newt pkg validate --json apps/blinky libs/broken\n"
+	validateCmdHelpEx += "  newt pkg validate --includes libs/a"
+
+	validateCmd := &cobra.Command{
+		Use:     "validate [pkg-name|pkg-dir...]",
+		Short:   "Check packages for pkg.yml problems",
+		Long:    validateCmdHelpText,
+		Example: validateCmdHelpEx,
+		Run:     pkgValidateCmd,
+	}
+
+	validateCmd.PersistentFlags().BoolVar(&pkgValidateJson, "json", false,
+		"Output the problems as JSON.")
+	validateCmd.PersistentFlags().BoolVar(&pkgValidateIncludes, "includes",
+		false, "Also check that the include directories specified by -I "+
+			"options in pkg.cflags and pkg.cxxflags exist.  Relative "+
+			"directories are resolved against the project root.")
+
+	pkgCmd.AddCommand(validateCmd)
+
+	searchCmdHelpText := "Search the project's packages by keyword.  A " +
+		"package matches a term if its pkg.keywords or pkg.description " +
+		"contains the term; matching is case-insensitive.  If several terms " +
+		"are specified, a package must match all of them."
+	searchCmdHelpEx := "  newt pkg search bluetooth\n"
+	searchCmdHelpEx += "  newt pkg search ble mesh"
+
+	searchCmd := &cobra.Command{
+		Use:     "search <keyword> [keyword...]",
+		Short:   "Search packages by keyword",
+		Long:    searchCmdHelpText,
+		Example: searchCmdHelpEx,
+		Run:     pkgSearchCmd,
+	}
+
+	pkgCmd.AddCommand(searchCmd)
+
+	scanCmdHelpText := "Scan the project's repos for packages and report " +
+		"the problems encountered.  Each problem has a category: " +
+		pkg.SCAN_WARNING_SEARCH_ERROR + " (a directory could not be " +
+		"read), " + pkg.SCAN_WARNING_LOAD_ERROR + " (a package could not " +
+		"be loaded), " + pkg.SCAN_WARNING_DUPLICATE_NAME + " (two packages " +
+		"in a repo have the same name), or " + pkg.SCAN_WARNING_ORPHAN_SYSCFG +
+		" (a syscfg.yml file in a directory without a pkg.yml file)."
+	scanCmdHelpEx := "  newt pkg scan\n"
+	scanCmdHelpEx += "  newt pkg scan --json"
+
+	scanCmd := &cobra.Command{
+		Use:     "scan",
+		Short:   "Report problems found while discovering packages",
+		Long:    scanCmdHelpText,
+		Example: scanCmdHelpEx,
+		Run:     pkgScanCmd,
+	}
+
+	scanCmd.PersistentFlags().BoolVar(&pkgScanJson, "json", false,
+		"Output the problems as JSON.")
+
+	pkgCmd.AddCommand(scanCmd)
+
+	createCmdHelpText := "Create a package with a minimal pkg.yml file and " +
+		"empty src and include directories.  Unlike \"newt pkg new\", " +
+		"this does not download a package template."
+	createCmdHelpEx := "  newt pkg create libs/mylib\n"
+	createCmdHelpEx += "  newt pkg create --type sdk sdk/vendor_sdk"
+
+	createCmd := &cobra.Command{
+		Use:     "create <package-name>",
+		Short:   "Create a minimal package",
+		Long:    createCmdHelpText,
+		Example: createCmdHelpEx,
+		Run:     pkgCreateCmd,
+	}
+
+	createCmd.PersistentFlags().StringVarP(&pkgCreateType, "type", "t",
+		"lib", "Package type (e.g., lib, app, bsp, sdk).")
+	createCmd.PersistentFlags().BoolVarP(&newtutil.NewtForce, "force", "f",
+		false, "Create the package even if it overwrites an existing one.")
+
+	pkgCmd.AddCommand(createCmd)
+
+	shadowCmdHelpText := "Report syscfg settings that are defined by more " +
+		"than one package with differing default values.  When such a " +
+		"setting is resolved, only one of the definitions takes effect.  " +
+		"All packages in all installed repos are checked; only " +
+		"unconditional definitions are considered."
+	shadowCmdHelpEx := "  newt pkg syscfg-shadow"
+
+	shadowCmd := &cobra.Command{
+		Use:     "syscfg-shadow",
+		Short:   "Report syscfg settings defined with different defaults",
+		Long:    shadowCmdHelpText,
+		Example: shadowCmdHelpEx,
+		Run:     pkgSyscfgShadowCmd,
+	}
+
+	pkgCmd.AddCommand(shadowCmd)
+
+	depsCmd := &cobra.Command{
+		Use:   "deps",
+		Short: "Add or remove package dependencies",
+		Long: "Edit a package's unconditional pkg.deps list.  The pkg.yml " +
+			"file is edited in place; comments and other fields are kept.",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Help()
+		},
+	}
+
+	depsAddCmd := &cobra.Command{
+		Use:     "add <pkg-name> <dep-name> [dep-name...]",
+		Short:   "Add dependencies to a package",
+		Example: "  newt pkg deps add apps/myapp libs/mylib",
+		Run:     pkgDepsAddCmd,
+	}
+	depsCmd.AddCommand(depsAddCmd)
+
+	depsRemoveCmd := &cobra.Command{
+		Use:     "remove <pkg-name> <dep-name> [dep-name...]",
+		Short:   "Remove dependencies from a package",
+		Example: "  newt pkg deps remove apps/myapp libs/mylib",
+		Run:     pkgDepsRemoveCmd,
+	}
+	depsCmd.AddCommand(depsRemoveCmd)
+
+	pkgCmd.AddCommand(depsCmd)
 }
@@ -0,0 +1,65 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"path/filepath"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/pkg"
+	"mynewt.apache.org/newt/newt/testutil"
+)
+
+func TestPkgValidateProblems(t *testing.T) {
+	proj, cleanup := testutil.NewProject(t, map[string]string{
+		"libs/broken/pkg.yml": "pkg.name: libs/broken\npkg.type: bogus\n",
+	})
+	defer cleanup()
+
+	brokenYml := filepath.Join(proj.Path(), "libs/broken",
+		pkg.PACKAGE_FILE_NAME)
+
+	// With no arguments, packages that failed to load are checked too.
+	problems := pkgValidateProblems(nil)
+	if len(problems) != 1 {
+		t.Fatalf("got %d problems (%v); want 1", len(problems), problems)
+	}
+	if problems[0].Severity != pkg.PROBLEM_SEVERITY_ERROR {
+		t.Errorf("problem has severity %s; want %s", problems[0].Severity,
+			pkg.PROBLEM_SEVERITY_ERROR)
+	}
+	if problems[0].File != brokenYml {
+		t.Errorf("problem in %s; want %s", problems[0].File, brokenYml)
+	}
+
+	// A directory argument is checked even though its package failed to
+	// load.
+	problems = pkgValidateProblems([]string{"libs/broken"})
+	if len(problems) != 1 || problems[0].File != brokenYml {
+		t.Errorf("libs/broken: got problems %v; want one in %s", problems,
+			brokenYml)
+	}
+
+	// A valid package has no problems.
+	problems = pkgValidateProblems([]string{"libs/a"})
+	if len(problems) != 0 {
+		t.Errorf("libs/a: got problems %v; want none", problems)
+	}
+}
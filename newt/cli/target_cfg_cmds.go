@@ -46,6 +46,8 @@ import (
 	"mynewt.apache.org/newt/util"
 )
 
+var syscfgExportFormat string
+
 func printSetting(entry syscfg.CfgEntry) {
 	util.StatusMessage(util.VERBOSITY_DEFAULT,
 		"  * Setting: %s\n", entry.Name)
@@ -330,6 +332,37 @@ func targetConfigFlatCmd(cmd *cobra.Command, args []string) {
 	}
 }
 
+func targetSyscfgExportCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		NewtUsage(cmd,
+			util.NewNewtError("Must specify exactly one target or unittest name"))
+	}
+
+	if syscfgExportFormat != "header" {
+		NewtUsage(cmd, util.FmtNewtError(
+			"Unsupported export format \"%s\"; supported formats: header",
+			syscfgExportFormat))
+	}
+
+	TryGetProject()
+
+	b, err := TargetBuilderForTargetOrUnittest(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	res := targetBuilderConfigResolve(b)
+	if errText := res.Cfg.ErrorText(); errText != "" {
+		NewtUsage(nil, util.NewNewtError(errText))
+	}
+
+	buf := bytes.Buffer{}
+	lpkgs := resolve.RpkgSliceToLpkgSlice(res.MasterSet.Rpkgs)
+	syscfg.WriteHeader(res.Cfg, lpkgs, &buf)
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT, "%s", buf.String())
+}
+
 func valSettingString(vs val.ValSetting) string {
 	intVal, _ := vs.IntVal()
 
@@ -791,8 +824,9 @@ func targetConfigInitCmd(cmd *cobra.Command, args []string) {
 		}
 		util.StatusMessage(util.VERBOSITY_DEFAULT, "\n")
 
-		fmt.Printf("Overwrite them? (y/N): ")
-		rsp := PromptYesNo(false)
+		dflt := DestructivePromptDefault()
+		fmt.Printf("Overwrite them? %s: ", PromptYesNoHint(dflt))
+		rsp := PromptYesNo(dflt)
 		if !rsp {
 			return
 		}
@@ -912,6 +946,26 @@ func targetCfgCmdAll() []*cobra.Command {
 		return append(targetList(), unittestList()...)
 	})
 
+	syscfgExportCmd := &cobra.Command{
+		Use:   "syscfg-export <target>",
+		Short: "Export a target's effective system configuration",
+		Long: "Export a target's effective system configuration for use " +
+			"outside of newt.  The header format produces the same " +
+			"MYNEWT_VAL_<setting> definitions as the syscfg.h file " +
+			"generated during a build.",
+		Run: targetSyscfgExportCmd,
+	}
+
+	syscfgExportCmd.Flags().StringVar(&syscfgExportFormat, "format",
+		"header", "Output format; only \"header\" is supported")
+	syscfgExportCmd.Flags().StringVarP(&util.InjectSyscfg, "syscfg", "S", "",
+		"Injected syscfg settings, key=value pairs separated by colon")
+
+	cmds = append(cmds, syscfgExportCmd)
+	AddTabCompleteFn(syscfgExportCmd, func() []string {
+		return append(targetList(), unittestList()...)
+	})
+
 	logHelpText := "View a target's log configuration"
 
 	logCmd := &cobra.Command{
@@ -20,27 +20,111 @@
 package cli
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/kballard/go-shellquote"
 	"github.com/spf13/cobra"
 
 	"mynewt.apache.org/newt/newt/builder"
+	"mynewt.apache.org/newt/newt/cfgv"
+	"mynewt.apache.org/newt/newt/config"
 	"mynewt.apache.org/newt/newt/newtutil"
 	"mynewt.apache.org/newt/newt/pkg"
+	"mynewt.apache.org/newt/newt/project"
 	"mynewt.apache.org/newt/newt/resolve"
 	"mynewt.apache.org/newt/newt/syscfg"
 	"mynewt.apache.org/newt/newt/target"
 	"mynewt.apache.org/newt/util"
+	"mynewt.apache.org/newt/yaml"
 )
 
 var amendDelete bool = false
+var amendPrefix bool = false
+var amendWarnRedundant bool = false
+var amendIfExists bool = false
+var amendTargets []string
 var showAll bool = false
+var showMissing bool = false
+var showResolveRefs bool = false
+var showBaseline string
+var showDefaultsSource bool = false
+var showColor string = "auto"
+var showGroup string
+var showOnlyOverrides bool = false
+var showOrder string = "alpha"
+var showResolveFlags bool = false
+var showWatch bool = false
+var showInterval time.Duration = time.Second
+var showWidth int
+var showHash bool = false
+var showAppOnly bool = false
+var showEffective bool = false
+
+// Set by `target show` according to --color and the environment.
+var showColorEnabled bool = false
+
+// Set by `target show` according to --width and the terminal; 0 disables
+// wrapping.
+var showWrapWidth int
+
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+	ansiCyan  = "\033[36m"
+)
+
 var listAll bool = false
+var noForeignWarn bool = false
+var targetRepoName string
+var flagsJson bool = false
+var flagsResolved bool = false
+var importReplace bool = false
+var copySyscfgReplace bool = false
+var copyMergeSyscfg bool = false
+var copyPreferSource bool = false
+var lockUndo bool = false
+var setIfUnset bool = false
+var setAllowUnknown bool = false
+var setNoSave bool = false
+var flagExpandHome bool = false
+var targetBatch bool = false
+var targetAtomic bool = false
+var depTree bool = false
+var depLongest bool = false
+var depFlat bool = false
+var depRoot string
+var depDepth int
+var depvizCombined bool = false
+var depvizFocus string
+var depFormat string = builder.DEP_GRAPH_FORMAT_TEXT
+var delSafe bool = false
+var depReverse bool = false
+var depExclude []string
+var depTimings bool = false
+var depWarningsAsErrors bool = false
+
+// The number of warnings emitted while resolving a dep or revdep graph.
+var depWarningCount int
+var copyVerify bool = false
+var revdepDirect bool = false
+var depdiffAdded bool = false
+var depdiffRemoved bool = false
+var syscfgStrict bool = false
+var createCopyFrom string
 
 // target variables that can have values amended with the amend command.
 var amendVars = []string{"aflags", "cflags", "cxxflags", "lflags", "syscfg"}
@@ -48,6 +132,44 @@ var amendVars = []string{"aflags", "cflags", "cxxflags", "lflags", "syscfg"}
 var setVars = []string{"aflags", "app", "build_profile", "bsp", "cflags",
 	"cxxflags", "lflags", "loader", "syscfg"}
 
+// target variables containing build flags.
+var flagVars = []string{"cflags", "cxxflags", "aflags", "lflags"}
+
+// target variables that a fully configured target must specify.
+var requiredVars = []string{"app", "bsp", "build_profile"}
+
+// target variables that name packages.
+var pkgRefVars = []string{"app", "bsp", "loader"}
+
+// Returns an error if the target is locked and the user did not specify
+// --force.  Also warns if the target belongs to an installed repo rather than
+// the local project, unless the user specified --no-foreign-warn.
+func checkTargetUnlocked(t *target.Target) error {
+	if t.Locked() && !newtutil.NewtForce {
+		return util.FmtNewtError(
+			"Target %s is locked; use --force to modify it", t.FullName())
+	}
+
+	if !t.Package().Repo().IsLocal() && !noForeignWarn {
+		util.ErrorMessage(util.VERBOSITY_QUIET,
+			"Warning: target %s belongs to repo %s; changes are lost when "+
+				"the repo is upgraded\n",
+			t.FullName(), t.Package().Repo().Name())
+	}
+
+	return nil
+}
+
+// Returns the name to display for a target in list and show output.  Targets
+// from installed repos are marked so they aren't mistaken for local ones.
+func targetDisplayName(t *target.Target) string {
+	if t.Package().Repo().IsLocal() {
+		return t.FullName()
+	}
+
+	return t.FullName() + " (foreign)"
+}
+
 func resolveExistingTargetArg(arg string) (*target.Target, error) {
 	t := ResolveTarget(arg)
 	if t == nil {
@@ -57,6 +179,61 @@ func resolveExistingTargetArg(arg string) (*target.Target, error) {
 	return t, nil
 }
 
+// Resolves a list of target selectors to a sorted, duplicate-free list of
+// targets.  A selector is either a target name or a glob pattern (e.g.,
+// "prod_*").  A pattern matches a target's full name, or its short name if the
+// target is in the local "targets" directory.  Each pattern must match at
+// least one target.
+func resolveTargetSelectors(selectors []string) ([]*target.Target, error) {
+	tmap := map[string]*target.Target{}
+
+	for _, sel := range selectors {
+		if !strings.ContainsAny(sel, "*?[") {
+			t, err := resolveExistingTargetArg(sel)
+			if err != nil {
+				return nil, err
+			}
+			tmap[t.FullName()] = t
+			continue
+		}
+
+		matched := false
+		for name, t := range target.GetTargets() {
+			shortName := strings.TrimPrefix(name, TARGET_DEFAULT_DIR+"/")
+
+			ok, err := path.Match(sel, name)
+			if err != nil {
+				return nil, util.FmtNewtError(
+					"Invalid target pattern \"%s\": %s", sel, err.Error())
+			}
+			if !ok {
+				ok, _ = path.Match(sel, shortName)
+			}
+			if ok {
+				tmap[name] = t
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, util.FmtNewtError(
+				"No targets match pattern: %s", sel)
+		}
+	}
+
+	names := make([]string, 0, len(tmap))
+	for name, _ := range tmap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	targets := make([]*target.Target, len(names))
+	for i, name := range names {
+		targets[i] = tmap[name]
+	}
+
+	return targets, nil
+}
+
 // Tells you if a target's directory contains extra user files (i.e., files
 // other than pkg.yml).
 func targetContainsUserFiles(t *target.Target) (bool, error) {
@@ -79,55 +256,394 @@ func targetContainsUserFiles(t *target.Target) (bool, error) {
 	return userFiles, nil
 }
 
-func pkgVarSliceString(pack *pkg.LocalPackage, key string) string {
-	vals, err := pack.PkgY.GetValStringSlice(key, nil)
-	util.OneTimeWarningError(err)
+// Indicates whether the specified target variable names a package (app, bsp,
+// or loader).
+func isPkgRefVar(name string) bool {
+	for _, v := range pkgRefVars {
+		if v == name {
+			return true
+		}
+	}
 
-	sort.Strings(vals)
-	var buffer bytes.Buffer
-	for _, v := range vals {
-		buffer.WriteString(v)
-		buffer.WriteString(" ")
+	return false
+}
+
+// Indicates whether the specified target variable is one of the build flag
+// lists (aflags, cflags, cxxflags, or lflags).
+func isBuildFlagVar(name string) bool {
+	switch strings.TrimPrefix(name, "target.") {
+	case "aflags", "cflags", "cxxflags", "lflags":
+		return true
+	default:
+		return false
 	}
-	return buffer.String()
 }
 
-//Process amend command for syscfg target variable
-func amendSysCfg(value string, t *target.Target) error {
+// Replaces a leading "~" in each word of a build flag value with the user's
+// home directory.  A "~" is also expanded when it follows a single-letter
+// option (e.g., "-I~/sdk/include") or an '=' character.  Since the result is
+// specific to this machine, a warning is displayed whenever a value changes.
+func expandHomeInFlagValue(name string, val string) (string, error) {
+	if !strings.Contains(val, "~") {
+		return val, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", util.ChildNewtError(err)
+	}
+
+	expandAt := func(word string, idx int) string {
+		rest := word[idx+1:]
+		if rest != "" && !strings.HasPrefix(rest, "/") {
+			// "~user" syntax is not supported.
+			return word
+		}
+		return word[:idx] + home + rest
+	}
+
+	words := strings.Split(val, " ")
+	for i, word := range words {
+		switch {
+		case strings.HasPrefix(word, "~"):
+			words[i] = expandAt(word, 0)
+		case len(word) > 2 && word[0] == '-' && word[2] == '~':
+			words[i] = expandAt(word, 2)
+		case strings.Contains(word, "=~"):
+			words[i] = expandAt(word, strings.Index(word, "=~")+1)
+		}
+	}
+
+	expVal := strings.Join(words, " ")
+	if expVal != val {
+		util.ErrorMessage(util.VERBOSITY_QUIET,
+			"Warning: expanded ~ in %s value; the stored path is specific "+
+				"to this machine: \"%s\"\n", name, expVal)
+	}
+
+	return expVal, nil
+}
+
+// Cleans up a build flag value passed to the set or amend command.  Values
+// pasted from a shell sometimes retain surrounding quotes or stray whitespace;
+// these are removed and a warning is displayed.  A value beginning with a
+// backslash is used verbatim (minus the backslash).  This allows flags which
+// legitimately need quotes (e.g., some aflags) to be specified.
+func normalizeBuildFlagValue(name string, val string) string {
+	if strings.HasPrefix(val, "\\") {
+		return strings.TrimPrefix(val, "\\")
+	}
+
+	normVal := strings.TrimSpace(val)
+	if len(normVal) >= 2 {
+		first := normVal[0]
+		last := normVal[len(normVal)-1]
+		if (first == '"' || first == '\'') && last == first {
+			normVal = normVal[1 : len(normVal)-1]
+		}
+	}
+	normVal = strings.Join(strings.Fields(normVal), " ")
+
+	if normVal != val {
+		util.ErrorMessage(util.VERBOSITY_QUIET,
+			"Warning: removed stray quotes or whitespace from %s value: "+
+				"\"%s\" --> \"%s\"\n", name, val, normVal)
+	}
+
+	return normVal
+}
+
+// Resolves the target's syscfg configuration.  Returns nil if the target
+// cannot be resolved (e.g., it is incomplete).
+func resolveTargetCfg(t *target.Target) *syscfg.Cfg {
+	b, err := builder.NewTargetBuilder(t)
+	if err != nil {
+		return nil
+	}
+
+	res, err := b.Resolve()
+	if err != nil {
+		return nil
+	}
+
+	return &res.Cfg
+}
+
+// Checks each of the specified syscfg values against the definition of the
+// corresponding setting, including its valid choices and ranges.  The check is
+// skipped if the target cannot be resolved.  Mismatches are reported as
+// warnings, or as an error if the `--strict` option was specified.
+func checkSyscfgTypes(t *target.Target, vals map[string]string) error {
+	cfg := resolveTargetCfg(t)
+	if cfg == nil {
+		return nil
+	}
+
+	// Range expressions are evaluated with all the new values applied.
+	settings := cfgv.NewSettings(cfg.SettingValues())
+	for name, val := range vals {
+		settings.Set(name, val)
+	}
+
+	names := make([]string, 0, len(vals))
+	for name, _ := range vals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry, ok := cfg.Settings[name]
+		if !ok {
+			continue
+		}
+
+		err := entry.CheckValueType(vals[name])
+		if err == nil {
+			err = cfg.CheckValueRange(name, settings)
+		}
+		if err != nil {
+			if syscfgStrict {
+				return err
+			}
+			util.ErrorMessage(util.VERBOSITY_QUIET, "Warning: %s\n",
+				err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Merges the specified settings into the target's syscfg.vals.  If `del` is
+// true, the specified settings are removed instead; a message is displayed for
+// each one the target does not set.  Returns the number of settings added,
+// changed, or removed.
+func mergeSysCfg(t *target.Target, vals map[string]string, del bool) int {
 	// Get the current syscfg.vals name-value pairs
 	sysVals, err := t.Package().SyscfgY.GetValStringMapString("syscfg.vals", nil)
 	util.OneTimeWarningError(err)
 
+	changed := 0
+	if del {
+		names := make([]string, 0, len(vals))
+		for k, _ := range vals {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		for _, k := range names {
+			if _, ok := sysVals[k]; !ok {
+				util.StatusMessage(util.VERBOSITY_DEFAULT,
+					"Target %s: nothing to delete: syscfg %s not present\n",
+					t.FullName(), k)
+				continue
+			}
+			delete(sysVals, k)
+			changed++
+		}
+	} else {
+		// The target might not have a syscfg.vals map yet.
+		if sysVals == nil {
+			sysVals = map[string]string{}
+		}
+		for k, v := range vals {
+			if cur, ok := sysVals[k]; !ok || cur != v {
+				sysVals[k] = v
+				changed++
+			}
+		}
+	}
+
+	if changed > 0 {
+		itfMap := util.StringMapStringToItfMapItf(sysVals)
+		t.Package().SyscfgY.Replace("syscfg.vals", itfMap)
+	}
+
+	return changed
+}
+
+// Evaluates relative syscfg assignments of the form "NAME+=N" and "NAME-=N".
+// These are parsed as a setting name ending in '+' or '-'.  Each is replaced
+// in `vals` with an absolute assignment computed from the setting's current
+// value: the value in the target's syscfg.yml, or the resolved value if the
+// target does not override the setting.
+func applySyscfgIncrements(t *target.Target, vals map[string]string) error {
+	var cfg *syscfg.Cfg
+	cfgResolved := false
+
+	for key, delta := range vals {
+		name := strings.TrimRight(key, "+-")
+		if name == key {
+			continue
+		}
+		if len(key)-len(name) != 1 || name == "" {
+			return util.FmtNewtError("Invalid syscfg setting: %s=%s",
+				key, delta)
+		}
+		if amendDelete {
+			return util.FmtNewtError(
+				"Cannot delete a relative syscfg setting: %s=%s", key, delta)
+		}
+
+		op := key[len(key)-1:]
+		d, err := util.AtoiNoOct(delta)
+		if err != nil {
+			return util.FmtNewtError(
+				"Invalid increment for syscfg setting %s: \"%s\"", name, delta)
+		}
+
+		tvals, err := t.Package().SyscfgY.GetValStringMapString(
+			"syscfg.vals", nil)
+		util.OneTimeWarningError(err)
+
+		cur, ok := tvals[name]
+		if !ok {
+			if !cfgResolved {
+				cfg = resolveTargetCfg(t)
+				cfgResolved = true
+			}
+			if cfg != nil {
+				if entry, ok2 := cfg.Settings[name]; ok2 {
+					cur, ok = entry.Value, true
+				}
+			}
+		}
+		if !ok {
+			return util.FmtNewtError(
+				"Cannot apply %s= to syscfg setting %s: current value unknown",
+				op, name)
+		}
+
+		c, err := util.AtoiNoOct(cur)
+		if err != nil {
+			return util.FmtNewtError(
+				"Cannot apply %s= to syscfg setting %s: current value "+
+					"\"%s\" is not numeric", op, name, cur)
+		}
+
+		if op == "+" {
+			c += d
+		} else {
+			c -= d
+		}
+
+		delete(vals, key)
+		if strings.HasPrefix(strings.ToLower(cur), "0x") && c >= 0 {
+			vals[name] = fmt.Sprintf("0x%x", c)
+		} else {
+			vals[name] = strconv.Itoa(c)
+		}
+	}
+
+	return nil
+}
+
+// Warns about each of the specified settings whose value equals the setting's
+// default.  Nothing is reported if the target cannot be resolved.
+func warnRedundantSyscfg(t *target.Target, vals map[string]string) {
+	cfg := resolveTargetCfg(t)
+	if cfg == nil {
+		return
+	}
+
+	names := make([]string, 0, len(vals))
+	for name, _ := range vals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry, ok := cfg.Settings[name]
+		if ok && entry.History[0].Value == vals[name] {
+			util.ErrorMessage(util.VERBOSITY_QUIET,
+				"Warning: %s=%s is redundant; it matches the default "+
+					"defined in %s\n",
+				name, vals[name], entry.History[0].Source.FullName())
+		}
+	}
+}
+
+// Removes the settings that the target's `syscfg.vals` does not already
+// contain from the specified set of name-value pairs (`--if-exists`).
+func skipNewSyscfg(t *target.Target, vals map[string]string) {
+	tvals, err := t.Package().SyscfgY.GetValStringMapString("syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+
+	keys := make([]string, 0, len(vals))
+	for key, _ := range vals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := strings.TrimRight(key, "+-")
+		if _, ok := tvals[name]; !ok {
+			util.StatusMessage(util.VERBOSITY_DEFAULT,
+				"Target %s does not set syscfg %s; skipping\n",
+				t.FullName(), name)
+			delete(vals, key)
+		}
+	}
+}
+
+//Process amend command for syscfg target variable
+func amendSysCfg(value string, t *target.Target) (int, error) {
 	// Convert the input syscfg into name-value pairs
 	amendSysVals, err := syscfg.KeyValueFromStr(value)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	// Have current syscfg.vals in syscfg.yml file
-	if sysVals != nil {
-		// Either delete syscfg variable or replace with new value
-		for k, v := range amendSysVals {
-			if amendDelete {
-				delete(sysVals, k)
-			} else {
-				sysVals[k] = v
-			}
+
+	if amendIfExists {
+		skipNewSyscfg(t, amendSysVals)
+	}
+
+	if err := applySyscfgIncrements(t, amendSysVals); err != nil {
+		return 0, err
+	}
+
+	if !amendDelete {
+		if err := checkSyscfgTypes(t, amendSysVals); err != nil {
+			return 0, err
 		}
-	} else {
-		// No syscfg.vals in syscfg.yml file. Use all the new
-		// syscfg name-value  pairs if not deleting
-		if !amendDelete {
-			sysVals = amendSysVals
+		if amendWarnRedundant {
+			warnRedundantSyscfg(t, amendSysVals)
 		}
 	}
 
-	itfMap := util.StringMapStringToItfMapItf(sysVals)
-	t.Package().SyscfgY.Replace("syscfg.vals", itfMap)
-	return nil
+	return mergeSysCfg(t, amendSysVals, amendDelete), nil
+}
+
+// Returns the name of the macro defined by a "-D<name>[=<value>]" flag, or ""
+// if the flag does not define a macro.
+func flagMacroName(flag string) string {
+	if !strings.HasPrefix(flag, "-D") {
+		return ""
+	}
+
+	return strings.SplitN(strings.TrimPrefix(flag, "-D"), "=", 2)[0]
+}
+
+// Warns if `flag` defines a macro that one of `curFlags` already defines
+// (with any value).  Such definitions conflict at build time.
+func warnMacroRedefinition(varName string, flag string, curFlags []string) {
+	name := flagMacroName(flag)
+	if name == "" {
+		return
+	}
+
+	for _, curFlag := range curFlags {
+		if flagMacroName(curFlag) == name {
+			util.ErrorMessage(util.VERBOSITY_QUIET,
+				"Warning: %s: %s redefines macro %s already defined by %s\n",
+				varName, flag, name, curFlag)
+			return
+		}
+	}
 }
 
 //Process amend command for aflags, cflags, cxxflags, and lflags target variables.
-func amendBuildFlags(kv []string, t *target.Target) error {
+//When deleting, returns the number of flags removed.
+func amendBuildFlags(kv []string, t *target.Target) (int, error) {
 	pkgVar := "pkg." + kv[0]
 
 	curFlags, err := t.Package().PkgY.GetValStringSlice(pkgVar, nil)
@@ -137,6 +653,7 @@ func amendBuildFlags(kv []string, t *target.Target) error {
 
 	newFlags := []string{}
 	exist := false
+	removed := 0
 
 	// add flags
 	if !amendDelete {
@@ -150,412 +667,2507 @@ func amendBuildFlags(kv []string, t *target.Target) error {
 			}
 			// Add flag if flag is not already set
 			if !exist {
+				warnMacroRedefinition(kv[0], amendVal, curFlags)
 				newFlags = append(newFlags, amendVal)
 			}
 		}
 	} else {
-		// Delete Flag if it exist.
-		for _, curVal := range curFlags {
-			exist = false
-			for _, deleteVal := range amendFlags {
-				if deleteVal == curVal {
-					exist = true
-					break
-				}
-			}
-			// Not deleting this flag, add it to the set of new
-			// flags to save
-			if !exist {
-				newFlags = append(newFlags, curVal)
-			}
-		}
+		newFlags, removed = removeBuildFlags(curFlags, amendFlags, amendPrefix)
 	}
 	t.Package().PkgY.Replace(pkgVar, newFlags)
-	return nil
+	return removed, nil
 }
 
-func targetShowCmd(cmd *cobra.Command, args []string) {
-	TryGetProject()
-	targetNames := []string{}
-	if len(args) == 0 {
-		for name, t := range target.GetTargets() {
-			keep := func() bool {
-				// Don't display the special unittest target; this is used
-				// internally by newt, so the user doesn't need to know about
-				// it.
-				if strings.HasSuffix(name, "/unittest") {
-					return false
-				}
+// Removes each flag in `curFlags` that equals one of `delFlags`.  If `prefix`
+// is true, every flag that starts with one of `delFlags` is removed instead.
+// Returns the remaining flags and the number of flags removed.
+func removeBuildFlags(curFlags []string, delFlags []string,
+	prefix bool) ([]string, int) {
 
-				// Don't show foreign targets without the `-a` option.
-				if !showAll && !t.Package().Repo().IsLocal() {
-					return false
-				}
+	newFlags := []string{}
+	removed := 0
 
-				return true
-			}
+	for _, curVal := range curFlags {
+		exist := false
+		for _, deleteVal := range delFlags {
+			if deleteVal == curVal ||
+				(prefix && strings.HasPrefix(curVal, deleteVal)) {
 
-			if keep() {
-				targetNames = append(targetNames, name)
+				exist = true
+				break
 			}
 		}
-	} else {
-		targetSlice, err := ResolveTargets(args...)
-		if err != nil {
-			NewtUsage(cmd, err)
-		}
-
-		for _, t := range targetSlice {
-			targetNames = append(targetNames, t.FullName())
+		// Not deleting this flag, add it to the set of new flags to save.
+		if !exist {
+			newFlags = append(newFlags, curVal)
+		} else {
+			removed++
 		}
 	}
 
-	sort.Strings(targetNames)
-
-	for _, name := range targetNames {
-		kvPairs := map[string]string{}
+	return newFlags, removed
+}
 
-		util.StatusMessage(util.VERBOSITY_DEFAULT, name+"\n")
+// Determines whether a target should be included when listing all targets.
+// Foreign targets are only included if `all` is true or if they belong to the
+// repo specified with the `--repo` option.
+func keepListedTarget(name string, t *target.Target, all bool) bool {
+	// Don't display the special unittest target; this is used internally by
+	// newt, so the user doesn't need to know about it.
+	if strings.HasSuffix(name, "/unittest") {
+		return false
+	}
 
-		target := target.GetTargets()[name]
-		settings := target.TargetY.AllSettingsAsStrings()
-		for k, v := range settings {
-			kvPairs[strings.TrimPrefix(k, "target.")] = v
-		}
+	if targetRepoName != "" {
+		return t.Package().Repo().Name() == targetRepoName
+	}
 
-		// A few variables come from the base package rather than the target.
-		scfg, err := target.Package().SyscfgY.GetValStringMapString(
-			"syscfg.vals", nil)
-		util.OneTimeWarningError(err)
-		kvPairs["syscfg"] = syscfg.KeyValueToStr(scfg)
+	// Don't show foreign targets without the `-a` option.
+	if !all && !t.Package().Repo().IsLocal() {
+		return false
+	}
 
-		kvPairs["cflags"] = pkgVarSliceString(target.Package(), "pkg.cflags")
-		kvPairs["cxxflags"] = pkgVarSliceString(target.Package(), "pkg.cxxflags")
-		kvPairs["lflags"] = pkgVarSliceString(target.Package(), "pkg.lflags")
-		kvPairs["aflags"] = pkgVarSliceString(target.Package(), "pkg.aflags")
+	return true
+}
 
-		keys := []string{}
-		for k, _ := range kvPairs {
-			keys = append(keys, k)
+// Determines whether show output should be colored.  "auto" enables color
+// only if stdout is a terminal and the NO_COLOR environment variable is unset.
+func resolveShowColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if _, ok := os.LookupEnv("NO_COLOR"); ok {
+			return false, nil
 		}
-		sort.Strings(keys)
-		for _, k := range keys {
-			val := kvPairs[k]
-			if len(val) > 0 {
-				util.StatusMessage(util.VERBOSITY_DEFAULT, "    %s=%s\n",
-					k, kvPairs[k])
-			}
+		fi, err := os.Stdout.Stat()
+		if err != nil {
+			return false, nil
 		}
+		return fi.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, util.FmtNewtError(
+			"Invalid --color value: %s; must be auto, always, or never", mode)
 	}
 }
 
-func targetListCmd(cmd *cobra.Command, args []string) {
-	TryGetProject()
-	targetNames := []string{}
+// Wraps a string in the specified ANSI escape sequence if show output is
+// colored.
+func showColorize(s string, code string) string {
+	if !showColorEnabled {
+		return s
+	}
+	return code + s + ansiReset
+}
 
-	for name, t := range target.GetTargets() {
-		keep := func() bool {
-			// Don't display the special unittest target; this is used
-			// internally by newt, so the user doesn't need to know about
-			// it.
-			if strings.HasSuffix(name, "/unittest") {
-				return false
-			}
+// Determines the column at which show output wraps long values.  If --width
+// is not specified, values wrap at the terminal width when stdout is a
+// terminal, and are not wrapped otherwise.
+func resolveShowWidth(cmd *cobra.Command) (int, error) {
+	if cmd.Flags().Changed("width") {
+		if showWidth < 0 {
+			return 0, util.FmtNewtError(
+				"Invalid --width value: %d; must not be negative", showWidth)
+		}
+		return showWidth, nil
+	}
 
-			// Don't show foreign targets without the `-a` option.
-			if !listAll && !t.Package().Repo().IsLocal() {
-				return false
-			}
+	fi, err := os.Stdout.Stat()
+	if err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return 0, nil
+	}
 
-			return true
-		}
+	if w := util.TerminalWidth(os.Stdout); w > 0 {
+		return w, nil
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w, nil
+	}
 
-		if keep() {
-			targetNames = append(targetNames, name)
+	return 80, nil
+}
+
+// Formats a "    name=value" line of show output.  If the line is wider than
+// the wrap width, the value is broken at whitespace so that lists of flags
+// wrap at flag boundaries; continuation lines are indented to align with the
+// start of the value.  A single token wider than the line is never split.
+func showKvLine(name string, val string) string {
+	prefix := "    " + name + "="
+	line := "    " + showColorize(name, ansiCyan) + "=" + val
+	if showWrapWidth <= 0 || len(prefix)+len(val) <= showWrapWidth {
+		return line + "\n"
+	}
+
+	indent := strings.Repeat(" ", len(prefix))
+	line = "    " + showColorize(name, ansiCyan) + "="
+	col := len(prefix)
+	first := true
+	for _, tok := range strings.Fields(val) {
+		if !first && col+1+len(tok) > showWrapWidth {
+			line += "\n" + indent
+			col = len(indent)
+			first = true
+		}
+		if !first {
+			line += " "
+			col++
 		}
+		line += tok
+		col += len(tok)
+		first = false
 	}
 
-	sort.Strings(targetNames)
+	return line + "\n"
+}
 
-	for _, name := range targetNames {
-		util.StatusMessage(util.VERBOSITY_DEFAULT, name+"\n")
+// Ranks a variable name for `--order=logical`: package references first, then
+// other target variables, then build flags, then syscfg settings.
+func showKeyRank(key string) int {
+	switch {
+	case key == "app":
+		return 0
+	case key == "bsp":
+		return 1
+	case key == "loader":
+		return 2
+	case key == "build_profile":
+		return 3
+	case isBuildFlagVar(key):
+		return 5
+	case key == "syscfg" || strings.HasPrefix(key, "syscfg."):
+		return 6
+	default:
+		return 4
 	}
 }
 
-func targetCmakeCmd(cmd *cobra.Command, args []string) {
-	TryGetProject()
-
-	// Verify and resolve each specified package.
-	targets, err := ResolveTargets(args...)
-	if err != nil {
-		NewtUsage(cmd, err)
+// Sorts variable names for display according to the `--order` option.
+func sortShowKeys(keys []string) {
+	if showOrder != "logical" {
+		sort.Strings(keys)
 		return
 	}
 
-	if len(targets) != 1 {
-		NewtUsage(cmd, err)
-		return
+	sort.Slice(keys, func(i int, j int) bool {
+		ri := showKeyRank(keys[i])
+		rj := showKeyRank(keys[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+}
+
+func targetShowCmd(cmd *cobra.Command, args []string) {
+	var err error
+	showColorEnabled, err = resolveShowColor(showColor)
+	if err != nil {
+		NewtUsage(cmd, err)
 	}
 
-	err = builder.CMakeTargetGenerate(targets[0])
+	showWrapWidth, err = resolveShowWidth(cmd)
 	if err != nil {
-		NewtUsage(nil, err)
+		NewtUsage(cmd, err)
 	}
-}
 
-func targetSetCmd(cmd *cobra.Command, args []string) {
-	if len(args) < 2 {
-		NewtUsage(cmd,
-			util.NewNewtError("Must specify at least two arguments "+
-				"(target-name & k=v) to set"))
+	if showOrder != "alpha" && showOrder != "logical" {
+		NewtUsage(cmd, util.FmtNewtError(
+			"Invalid --order value: %s; must be alpha or logical", showOrder))
 	}
 
 	TryGetProject()
 
-	// Parse target name.
-	t, err := resolveExistingTargetArg(args[0])
-	if err != nil {
-		NewtUsage(cmd, err)
+	if showGroup != "" {
+		groupNames, err := readTargetGroup(showGroup)
+		if err != nil {
+			NewtUsage(nil, err)
+		}
+		args = append(args, groupNames...)
 	}
 
-	// Parse series of k=v pairs.  If an argument doesn't contain a '='
-	// character, display the valid values for the variable and quit.
-	vars := [][]string{}
-	for i := 1; i < len(args); i++ {
-		kv := strings.SplitN(args[i], "=", 2)
-		key := strings.TrimPrefix(kv[0], "target.")
-		supported := false
-		for _, v := range setVars {
-			if key == v {
-				supported = true
-				break
+	targetNames := []string{}
+	if len(args) == 0 {
+		for name, t := range target.GetTargets() {
+			if keepListedTarget(name, t, showAll) {
+				targetNames = append(targetNames, name)
 			}
 		}
+	} else {
+		targetSlice, err := ResolveTargets(args...)
+		if err != nil {
+			NewtUsage(cmd, err)
+		}
 
-		if !supported {
-			NewtUsage(cmd,
-				util.NewNewtError("Not a valid variable: "+key))
+		for _, t := range targetSlice {
+			if targetRepoName != "" &&
+				t.Package().Repo().Name() != targetRepoName {
+
+				continue
+			}
+			targetNames = append(targetNames, t.FullName())
 		}
-		if !strings.HasPrefix(kv[0], "target.") {
-			kv[0] = "target." + kv[0]
+	}
+
+	// An empty result is only an error if the user asked for something
+	// specific; a project without targets is not.
+	if len(targetNames) == 0 && (len(args) > 0 || targetRepoName != "") {
+		NewtUsage(nil, util.NewNewtError("No targets matched"))
+	}
+
+	sort.Strings(targetNames)
+
+	if showBaseline != "" && showMissing {
+		NewtUsage(cmd, util.NewNewtError(
+			"--baseline and --missing cannot be used together"))
+	}
+
+	if showHash && (showBaseline != "" || showMissing || showOnlyOverrides) {
+		NewtUsage(cmd, util.NewNewtError(
+			"--hash cannot be used with --baseline, --missing, or "+
+				"--only-overrides"))
+	}
+
+	if showAppOnly && (showBaseline != "" || showMissing || showHash ||
+		showOnlyOverrides) {
+
+		NewtUsage(cmd, util.NewNewtError(
+			"--app-only cannot be used with --baseline, --missing, --hash, "+
+				"or --only-overrides"))
+	}
+
+	if showWatch && showInterval <= 0 {
+		NewtUsage(cmd, util.NewNewtError("--interval must be positive"))
+	}
+
+	targetShowRender(cmd, targetNames)
+
+	if showWatch {
+		targetShowWatch(cmd, targetNames)
+	}
+}
+
+// Prints the specified targets according to the `show` options.
+func targetShowRender(cmd *cobra.Command, targetNames []string) {
+	var baseline *target.Target
+	if showBaseline != "" {
+		var err error
+		baseline, err = resolveExistingTargetArg(showBaseline)
+		if err != nil {
+			NewtUsage(cmd, err)
 		}
+	}
 
-		// Make sure it is a valid variable.
+	for _, name := range targetNames {
+		if baseline != nil {
+			targetShowDiffs(target.GetTargets()[name], baseline)
+			continue
+		}
 
-		if len(kv) == 1 {
-			// User entered a variable name without a value.
-			NewtUsage(cmd, nil)
+		if showHash {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "%s %s\n", name,
+				target.GetTargets()[name].ConfigHash())
+			continue
 		}
 
-		// Trim trailing slash from value.  This is necessary when tab
-		// completion is used to fill in the value.
-		kv[1] = strings.TrimSuffix(kv[1], "/")
+		kvPairs := target.GetTargets()[name].ToMap()
 
-		vars = append(vars, kv)
+		if showMissing {
+			missing := []string{}
+			for _, k := range requiredVars {
+				if kvPairs[k] == "" {
+					missing = append(missing, k)
+				}
+			}
+
+			if len(missing) > 0 {
+				util.StatusMessage(util.VERBOSITY_DEFAULT,
+					"%s: missing %s\n", name, strings.Join(missing, ", "))
+			}
+			continue
+		}
+
+		if showAppOnly {
+			targetShowAppOnly(target.GetTargets()[name])
+		} else if showOnlyOverrides {
+			targetShowOverrides(target.GetTargets()[name])
+		} else {
+			targetShowVars(target.GetTargets()[name])
+		}
+
+		if showResolveFlags {
+			targetShowResolvedFlags(target.GetTargets()[name])
+		}
 	}
 
-	// Set each specified variable in the target.
-	for _, kv := range vars {
-		// A few variables are special cases; they get set in the base package
-		// instead of the target.
-		if kv[0] == "target.syscfg" {
-			t.Package().SyscfgY.Clear()
-			kv, err := syscfg.KeyValueFromStr(kv[1])
-			if err != nil {
-				NewtUsage(cmd, err)
+	if showGroup != "" {
+		targetShowGroupSummary(targetNames)
+	}
+}
+
+// Lists the configuration files read while loading the specified targets and
+// the baseline target, if any.
+func targetShowCfgFiles(targetNames []string) []string {
+	names := append([]string{}, targetNames...)
+	if showBaseline != "" {
+		if t := ResolveTarget(showBaseline); t != nil {
+			names = append(names, t.FullName())
+		}
+	}
+
+	files := []string{}
+	for _, name := range names {
+		if t := target.GetTargets()[name]; t != nil {
+			files = append(files, t.Package().CfgFilenames()...)
+		}
+	}
+
+	return files
+}
+
+// Returns the modification time of each specified file.  A file that cannot
+// be stat'd maps to the zero time.
+func cfgFileMtimes(files []string) map[string]time.Time {
+	mtimes := make(map[string]time.Time, len(files))
+	for _, f := range files {
+		var mtime time.Time
+		if info, err := os.Stat(f); err == nil {
+			mtime = info.ModTime()
+		}
+		mtimes[f] = mtime
+	}
+
+	return mtimes
+}
+
+// Polls the configuration files of the specified targets and reprints the
+// targets whenever one of the files changes.  This function never returns;
+// the user interrupts newt to stop watching.
+func targetShowWatch(cmd *cobra.Command, targetNames []string) {
+	files := targetShowCfgFiles(targetNames)
+	mtimes := cfgFileMtimes(files)
+
+	for {
+		time.Sleep(showInterval)
+
+		cur := cfgFileMtimes(files)
+		changed := false
+		for f, mtime := range cur {
+			if !mtime.Equal(mtimes[f]) {
+				changed = true
+				break
 			}
+		}
+		if !changed {
+			continue
+		}
+		mtimes = cur
 
-			itfMap := util.StringMapStringToItfMapItf(kv)
-			t.Package().SyscfgY.Replace("syscfg.vals", itfMap)
-		} else if kv[0] == "target.cflags" ||
-			kv[0] == "target.cxxflags" ||
-			kv[0] == "target.lflags" ||
-			kv[0] == "target.aflags" {
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"\n[%s] configuration changed\n", time.Now().Format("15:04:05"))
 
-			kv[0] = "pkg." + strings.TrimPrefix(kv[0], "target.")
-			if kv[1] == "" {
-				// User specified empty value; delete variable.
-				t.Package().PkgY.Replace(kv[0], nil)
+		// Reload the project so that the targets are read from disk again.
+		// Keep watching if the edited files can't be parsed yet.
+		if err := ResetGlobalState(); err != nil {
+			NewtUsage(nil, err)
+		}
+		if _, err := project.TryGetProject(); err != nil {
+			util.ErrorMessage(util.VERBOSITY_QUIET, "Error: %s\n",
+				err.Error())
+			continue
+		}
+
+		present := []string{}
+		for _, name := range targetNames {
+			if target.GetTargets()[name] == nil {
+				util.ErrorMessage(util.VERBOSITY_QUIET,
+					"Warning: target %s no longer exists\n", name)
 			} else {
-				t.Package().PkgY.Replace(kv[0], strings.Fields(kv[1]))
+				present = append(present, name)
 			}
-		} else {
-			if kv[1] == "" {
-				// User specified empty value; delete variable.
-				t.TargetY.Delete(kv[0])
-			} else {
-				// Assign value to specified variable.
-				t.TargetY.Replace(kv[0], kv[1])
+		}
+
+		targetShowRender(cmd, present)
+
+		// Keep watching the files of targets that failed to load so that
+		// they are picked up again once fixed.
+		files = util.UniqueStrings(
+			append(files, targetShowCfgFiles(present)...))
+		mtimes = cfgFileMtimes(files)
+	}
+}
+
+// Prints a target's name followed by each of its variables that has a value.
+func targetShowVars(t *target.Target) {
+	kvPairs := t.ToMap()
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		showColorize(targetDisplayName(t), ansiBold)+"\n")
+
+	keys := []string{}
+	for k, _ := range kvPairs {
+		keys = append(keys, k)
+	}
+	sortShowKeys(keys)
+	for _, k := range keys {
+		val := kvPairs[k]
+		if k == "syscfg" && len(val) > 0 &&
+			(showDefaultsSource || showEffective) {
+
+			targetShowSyscfgSources(t)
+			continue
+		}
+		if len(val) > 0 {
+			if showResolveRefs && isPkgRefVar(k) {
+				val += " " + targetPkgRefString(t, k)
 			}
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "%s",
+				showKvLine(k, val))
 		}
 	}
+}
 
-	if err := t.Save(); err != nil {
-		NewtUsage(cmd, err)
+// Prints only the settings explicitly specified by a target: target.yml
+// variables, syscfg.vals entries, and build flags.  Settings that are
+// explicitly assigned their default value are marked as such.
+func targetShowOverrides(t *target.Target) {
+	const dfltNote = " (explicitly set to default)"
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		showColorize(targetDisplayName(t), ansiBold)+"\n")
+
+	lines := map[string]string{}
+
+	for k, v := range t.TargetY.AllSettingsAsStrings() {
+		name := strings.TrimPrefix(k, "target.")
+		note := ""
+		switch name {
+		case "build_profile":
+			if v == target.DEFAULT_BUILD_PROFILE {
+				note = dfltNote
+			}
+		case "header_size":
+			if hs, err := util.AtoiNoOct(v); err == nil &&
+				uint32(hs) == target.DEFAULT_HEADER_SIZE {
+				note = dfltNote
+			}
+		}
+		lines[name] = v + note
 	}
 
-	for _, kv := range vars {
-		if kv[1] == "" {
-			util.StatusMessage(util.VERBOSITY_DEFAULT,
-				"Target %s successfully unset %s\n", t.FullName(), kv[0])
-		} else {
-			util.StatusMessage(util.VERBOSITY_DEFAULT,
-				"Target %s successfully set %s to %s\n", t.FullName(), kv[0],
-				kv[1])
+	for _, k := range flagVars {
+		vals, err := t.Package().PkgY.GetValStringSlice("pkg."+k, nil)
+		util.OneTimeWarningError(err)
+		if len(vals) > 0 {
+			lines[k] = strings.Join(vals, " ")
 		}
 	}
-}
 
-func targetAmendCmd(cmd *cobra.Command, args []string) {
-	if len(args) < 2 {
-		NewtUsage(cmd,
-			util.NewNewtError("Must specify at least two arguments "+
-				"(target-name & variable=value) to append"))
+	vals, err := t.Package().SyscfgY.GetValStringMapString("syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+	if len(vals) > 0 {
+		cfg := resolveTargetCfg(t)
+		for name, v := range vals {
+			note := ""
+			if cfg != nil {
+				entry, ok := cfg.Settings[name]
+				if ok && entry.History[0].Value == v {
+					note = dfltNote
+				}
+			}
+			lines["syscfg."+name] = v + note
+		}
 	}
 
-	TryGetProject()
+	keys := make([]string, 0, len(lines))
+	for k, _ := range lines {
+		keys = append(keys, k)
+	}
+	sortShowKeys(keys)
 
-	// Parse target name.
-	t, err := resolveExistingTargetArg(args[0])
+	for _, k := range keys {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s",
+			showKvLine(k, lines[k]))
+	}
+}
+
+// Reads a target group file.  A group file is a YAML file with a `targets`
+// list naming the targets in the group.
+func readTargetGroup(path string) ([]string, error) {
+	yc, err := config.ReadFile(path)
 	if err != nil {
-		NewtUsage(cmd, err)
+		return nil, err
 	}
 
-	// Parse series of k=v pairs.  If an argument doesn't contain a '='
-	// character, display the valid values for the variable and quit.
-	vars := [][]string{}
-	for i := 1; i < len(args); i++ {
-		kv := strings.SplitN(args[i], "=", 2)
-		// Check that the variable can have values appended.
-		valid := false
-		for _, v := range amendVars {
-			if kv[0] == v {
-				valid = true
-				break
+	names, err := yc.GetValStringSlice("targets", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) == 0 {
+		return nil, util.FmtNewtError(
+			"Target group file \"%s\" does not list any targets", path)
+	}
+
+	return names, nil
+}
+
+// Prints the variables that are identical across all the specified targets,
+// followed by those that differ.
+func targetShowGroupSummary(targetNames []string) {
+	maps := make([]map[string]string, len(targetNames))
+	keySet := map[string]struct{}{}
+	for i, name := range targetNames {
+		maps[i] = target.GetTargets()[name].FlatMap()
+		for k, v := range maps[i] {
+			if v != "" {
+				keySet[k] = struct{}{}
 			}
 		}
-		if !valid {
-			NewtUsage(cmd,
-				util.NewNewtError("Cannot amend values for "+kv[0]))
+	}
+
+	keys := make([]string, 0, len(keySet))
+	for k, _ := range keySet {
+		keys = append(keys, k)
+	}
+	sortShowKeys(keys)
+
+	common := []string{}
+	divergent := []string{}
+	for _, k := range keys {
+		same := true
+		for _, m := range maps[1:] {
+			if m[k] != maps[0][k] {
+				same = false
+				break
+			}
 		}
 
-		if len(kv) == 1 {
-			// User entered a variable name without a '='
-			NewtUsage(cmd, nil)
+		if same {
+			common = append(common, k)
+		} else {
+			divergent = append(divergent, k)
 		}
-		kv[1] = strings.TrimSpace(kv[1])
-		if kv[1] == "" {
-			NewtUsage(cmd,
-				util.NewNewtError("Must provide a value to"+
-					" append for variable "+kv[0]))
+	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT, "\n%s\n",
+		showColorize("common", ansiBold))
+	for _, k := range common {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s",
+			showKvLine(k, maps[0][k]))
+	}
 
+	util.StatusMessage(util.VERBOSITY_DEFAULT, "\n%s\n",
+		showColorize("divergent", ansiBold))
+	for _, k := range divergent {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "    %s\n",
+			showColorize(k, ansiCyan))
+		for i, name := range targetNames {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "        %s: %s\n",
+				name, maps[i][k])
 		}
-		// Trim trailing slash from value.  This is necessary when tab
-		// completion is used to fill in the value.
-		kv[1] = strings.TrimSuffix(kv[1], "/")
-		vars = append(vars, kv)
 	}
-	for _, kv := range vars {
-		if kv[0] == "syscfg" {
-			err = amendSysCfg(kv[1], t)
-			if err != nil {
-				NewtUsage(cmd, err)
+}
+
+// Prints each of the target's syscfg values on its own line.  With
+// --include-defaults-source, each is annotated with "(default)" if the value
+// matches the setting's default or "(override)" if it does not.  With
+// --effective, values that reference other settings are shown resolved,
+// followed by the value as written.
+func targetShowSyscfgSources(t *target.Target) {
+	vals, err := t.Package().SyscfgY.GetValStringMapString("syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+
+	cfg := resolveTargetCfg(t)
+	if cfg == nil {
+		util.ErrorMessage(util.VERBOSITY_QUIET,
+			"Warning: cannot resolve target %s; syscfg sources unknown\n",
+			t.FullName())
+	}
+
+	names := make([]string, 0, len(vals))
+	for name, _ := range vals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT, "    %s:\n",
+		showColorize("syscfg", ansiCyan))
+	for _, name := range names {
+		val := vals[name]
+		source := ""
+		if cfg != nil {
+			entry, ok := cfg.Settings[name]
+			if showDefaultsSource {
+				if !ok {
+					source = " (undefined)"
+				} else if entry.History[0].Value == vals[name] {
+					source = " (default)"
+				} else {
+					source = " (override)"
+				}
 			}
-		} else if kv[0] == "cflags" ||
-			kv[0] == "cxxflags" ||
-			kv[0] == "lflags" ||
-			kv[0] == "aflags" {
-			err = amendBuildFlags(kv, t)
-			if err != nil {
-				NewtUsage(cmd, err)
+			if showEffective && ok && entry.Value != val {
+				val = entry.Value
+				source += " (from " + vals[name] + ")"
 			}
 		}
+
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "        %s=%s%s\n",
+			showColorize(name, ansiCyan), val, source)
 	}
-	if err := t.Save(); err != nil {
-		NewtUsage(cmd, err)
-	}
+}
 
-	for _, kv := range vars {
+// Prints the variables of a target whose values differ from those of a
+// baseline target.
+func targetShowDiffs(t *target.Target, baseline *target.Target) {
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		showColorize(targetDisplayName(t), ansiBold)+"\n")
+
+	for _, diff := range t.Compare(baseline) {
 		util.StatusMessage(util.VERBOSITY_DEFAULT,
-			"Amended %s for Target %s successfully\n",
-			kv[0], t.FullName())
+			"    %s=%s (baseline: %s)\n", showColorize(diff.Name, ansiCyan),
+			showColorize(diff.Value, ansiGreen),
+			showColorize(diff.OtherValue, ansiRed))
 	}
 }
 
-func targetCreateCmd(cmd *cobra.Command, args []string) {
-	if len(args) != 1 {
-		NewtUsage(cmd, util.NewNewtError("Missing target name"))
+// Describes the package that a target variable (app, bsp, or loader) refers
+// to, e.g., "(repo=apache-mynewt-core path=/.../hw/bsp/nordic_pca10040)".
+func targetPkgRefString(t *target.Target, varName string) string {
+	pack := t.PkgRef(varName)
+	if pack == nil {
+		return "(unresolved)"
+	}
+
+	return fmt.Sprintf("(repo=%s path=%s)", pack.Repo().Name(),
+		pack.BasePath())
+}
+
+// Collects a target's build flags.  Map key=flag-variable, value=flags.  If
+// `resolved` is true, the flags from the target's app and BSP packages are
+// included as well; these are the flags newt applies to every package in the
+// build.  Resolved flags are evaluated against the target's syscfg.
+func targetBuildFlags(t *target.Target, resolved bool) (
+	map[string][]string, error) {
+
+	flags := map[string][]string{}
+
+	if !resolved {
+		for _, k := range flagVars {
+			vals, err := t.Package().PkgY.GetValStringSlice("pkg."+k, nil)
+			util.OneTimeWarningError(err)
+
+			flags[k] = append([]string{}, vals...)
+		}
+
+		return flags, nil
+	}
+
+	b, err := builder.NewTargetBuilder(t)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := b.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	// Use the same priority order as the builder: target, app, bsp.
+	lpkgs := []*pkg.LocalPackage{t.Package()}
+	if app := t.App(); app != nil {
+		lpkgs = append(lpkgs, app)
+	}
+	if bsp := t.Bsp(); bsp != nil {
+		lpkgs = append(lpkgs, bsp)
+	}
+
+	for _, k := range flagVars {
+		flags[k] = []string{}
+		for _, lpkg := range lpkgs {
+			settings := res.Cfg.AllSettingsForLpkg(lpkg)
+			vals, err := lpkg.PkgY.GetValStringSlice("pkg."+k, settings)
+			util.OneTimeWarningError(err)
+
+			flags[k] = append(flags[k], vals...)
+		}
+		flags[k] = util.UniqueStrings(flags[k])
+	}
+
+	return flags, nil
+}
+
+// A build flag and the package that contributes it.  A global flag is applied
+// to every package in the build.
+type resolvedFlag struct {
+	Flag   string
+	Pkg    string
+	Global bool
+}
+
+// Collects the build flags contributed by every package in a target's
+// dependency closure.  Map key=flag-variable, value=flags.  Flags from the
+// target, app, and BSP packages are global and are listed first, in the
+// builder's priority order.  Flags from other packages only apply when that
+// package is compiled, except for lflags, which are all passed to the linker.
+func targetResolvedFlagSources(t *target.Target) (
+	map[string][]resolvedFlag, error) {
+
+	b, err := builder.NewTargetBuilder(t)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := b.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	rpkgs, err := b.DependencyClosure()
+	if err != nil {
+		return nil, err
+	}
+
+	globals := []*pkg.LocalPackage{t.Package()}
+	if app := t.App(); app != nil {
+		globals = append(globals, app)
+	}
+	if bsp := t.Bsp(); bsp != nil {
+		globals = append(globals, bsp)
+	}
+
+	lpkgs := append([]*pkg.LocalPackage{}, globals...)
+	for _, rpkg := range rpkgs {
+		isGlobal := false
+		for _, g := range globals {
+			if rpkg.Lpkg == g {
+				isGlobal = true
+				break
+			}
+		}
+		if !isGlobal {
+			lpkgs = append(lpkgs, rpkg.Lpkg)
+		}
+	}
+
+	flags := map[string][]resolvedFlag{}
+	for _, k := range flagVars {
+		for i, lpkg := range lpkgs {
+			settings := res.Cfg.AllSettingsForLpkg(lpkg)
+			vals, err := lpkg.PkgY.GetValStringSlice("pkg."+k, settings)
+			util.OneTimeWarningError(err)
+
+			for _, v := range vals {
+				flags[k] = append(flags[k], resolvedFlag{
+					Flag:   v,
+					Pkg:    lpkg.FullName(),
+					Global: i < len(globals),
+				})
+			}
+		}
+	}
+
+	return flags, nil
+}
+
+// Prints the resolved configuration attributable to the target's app package:
+// the syscfg settings the app defines or overrides, and the build flags it
+// contributes.  A setting whose effective value comes from a later override
+// is annotated with the overriding package.
+func targetShowAppOnly(t *target.Target) {
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		showColorize(targetDisplayName(t), ansiBold)+"\n")
+
+	app := t.App()
+	if app == nil {
+		NewtUsage(nil, util.FmtNewtError(
+			"Target %s does not specify an app package", t.FullName()))
+	}
+
+	b, err := builder.NewTargetBuilder(t)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	res, err := b.Resolve()
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	names := []string{}
+	for name, entry := range res.Cfg.Settings {
+		for _, point := range entry.History {
+			if point.Source == app {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT, "    %s\n",
+		showColorize("app "+app.FullName()+":", ansiBold))
+
+	if len(names) > 0 {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "        %s:\n",
+			showColorize("syscfg", ansiCyan))
+	}
+	for _, name := range names {
+		entry := res.Cfg.Settings[name]
+
+		source := "override"
+		if entry.PackageDef == app {
+			source = "defined"
+		}
+		last := entry.History[len(entry.History)-1].Source
+		if last != app {
+			source += "; overridden by " + last.FullName()
+		}
+
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "            %s=%s (%s)\n",
+			showColorize(name, ansiCyan), entry.Value, source)
+	}
+
+	settings := res.Cfg.AllSettingsForLpkg(app)
+	for _, k := range flagVars {
+		vals, err := app.PkgY.GetValStringSlice("pkg."+k, settings)
+		util.OneTimeWarningError(err)
+
+		if len(vals) > 0 {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "        %s=%s\n",
+				showColorize(k, ansiCyan), strings.Join(vals, " "))
+		}
+	}
+}
+
+// Prints the build flags contributed by each package in the target's
+// dependency closure.
+func targetShowResolvedFlags(t *target.Target) {
+	flags, err := targetResolvedFlagSources(t)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT, "    %s\n",
+		showColorize("resolved flags:", ansiBold))
+	for _, k := range flagVars {
+		if len(flags[k]) == 0 {
+			continue
+		}
+
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "        %s:\n",
+			showColorize(k, ansiCyan))
+		for _, f := range flags[k] {
+			scope := ""
+			if f.Global {
+				scope = ", global"
+			}
+			util.StatusMessage(util.VERBOSITY_DEFAULT,
+				"            %s (%s%s)\n", f.Flag, f.Pkg, scope)
+		}
+	}
+}
+
+func targetFlagsCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		NewtUsage(cmd, util.NewNewtError("Must specify exactly one target"))
+	}
+
+	TryGetProject()
+
+	t, err := resolveExistingTargetArg(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	flags, err := targetBuildFlags(t, flagsResolved)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	if flagsJson {
+		j, err := json.MarshalIndent(flags, "", "    ")
+		if err != nil {
+			NewtUsage(nil, util.ChildNewtError(err))
+		}
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", string(j))
+		return
+	}
+
+	for _, k := range flagVars {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s=%s\n",
+			k, strings.Join(flags[k], " "))
+	}
+}
+
+// Converts a path specified in a build flag to a canonical form: relative to
+// the project directory (where builds run), cleaned, and with forward
+// slashes.  Returns false if the path is outside the project; such a path
+// can't be made machine-independent and is returned unchanged.  Paths are
+// not made package-relative because the compiler doesn't resolve them that
+// way.
+func normalizeFlagPath(projPath string, p string) (string, bool) {
+	if filepath.IsAbs(p) {
+		rel, err := filepath.Rel(projPath, p)
+		if err != nil {
+			return p, false
+		}
+		p = rel
+	}
+
+	p = filepath.ToSlash(filepath.Clean(p))
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return p, false
+	}
+
+	return p, true
+}
+
+// Normalizes the -I and -L paths in a list of build flags.  Both "-I<dir>"
+// and "-I <dir>" forms are recognized; the path of the latter may be in the
+// next list element.  Returns the new flags and the number of paths changed.
+// Only elements containing a changed path are rewritten, and each keeps its
+// form.  Paths outside the project are reported and left as is.
+func normalizeFlagPaths(projPath string, varName string,
+	flags []string) ([]string, int) {
+
+	newFlags := make([]string, len(flags))
+	changed := 0
+
+	// Option whose path is the next token ("-I <dir>" form).
+	pendingOpt := ""
+
+	for i, f := range flags {
+		newFlags[i] = f
+
+		tokens := strings.Fields(f)
+		entryChanged := false
+		for j, tok := range tokens {
+			opt := pendingOpt
+			prefix := ""
+			p := tok
+
+			if opt != "" {
+				pendingOpt = ""
+			} else {
+				if strings.HasPrefix(tok, "-I") {
+					opt = "-I"
+				} else if strings.HasPrefix(tok, "-L") {
+					opt = "-L"
+				} else {
+					continue
+				}
+
+				prefix = opt
+				p = strings.TrimPrefix(tok, opt)
+				if p == "" {
+					pendingOpt = opt
+					continue
+				}
+			}
+
+			norm, ok := normalizeFlagPath(projPath, p)
+			if !ok {
+				util.ErrorMessage(util.VERBOSITY_QUIET,
+					"Warning: %s: %s%s is outside the project; not "+
+						"normalized\n", varName, opt, p)
+				continue
+			}
+
+			if norm != p {
+				util.StatusMessage(util.VERBOSITY_DEFAULT,
+					"%s: %s%s --> %s%s\n", varName, opt, p, opt, norm)
+				tokens[j] = prefix + norm
+				entryChanged = true
+				changed++
+			}
+		}
+
+		if entryChanged {
+			newFlags[i] = strings.Join(tokens, " ")
+		}
+	}
+
+	return newFlags, changed
+}
+
+func targetNormalizePathsCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		NewtUsage(cmd, util.NewNewtError("Must specify exactly one target"))
+	}
+
+	proj := TryGetProject()
+
+	t, err := resolveExistingTargetArg(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	if err := checkTargetUnlocked(t); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	total := 0
+	for _, k := range flagVars {
+		flags, err := t.Package().PkgY.GetValStringSlice("pkg."+k, nil)
+		util.OneTimeWarningError(err)
+		if len(flags) == 0 {
+			continue
+		}
+
+		newFlags, changed := normalizeFlagPaths(proj.Path(), k, flags)
+		if changed > 0 {
+			t.Package().PkgY.Replace("pkg."+k, newFlags)
+			total += changed
+		}
+	}
+
+	if total == 0 {
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"Target %s paths are already normalized\n", t.FullName())
+		return
+	}
+
+	if err := t.Save(); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		"Normalized %d path(s) in target %s\n", total, t.FullName())
+}
+
+func targetListCmd(cmd *cobra.Command, args []string) {
+	if len(args) > 0 && targetRepoName != "" {
+		NewtUsage(cmd, util.NewNewtError(
+			"--repo cannot be used with target names; use target show"))
+	}
+
+	TryGetProject()
+	targetNames := []string{}
+
+	for name, t := range target.GetTargets() {
+		if keepListedTarget(name, t, listAll) {
+			targetNames = append(targetNames, name)
+		}
+	}
+
+	sort.Strings(targetNames)
+
+	for _, name := range targetNames {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n",
+			targetDisplayName(target.GetTargets()[name]))
+	}
+}
+
+func targetCmakeCmd(cmd *cobra.Command, args []string) {
+	TryGetProject()
+
+	// Verify and resolve each specified package.
+	targets, err := ResolveTargets(args...)
+	if err != nil {
+		NewtUsage(cmd, err)
+		return
+	}
+
+	if len(targets) != 1 {
+		NewtUsage(cmd, err)
+		return
+	}
+
+	err = builder.CMakeTargetGenerate(targets[0])
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+}
+
+// The contents of a target's configuration files, saved so that a failed
+// `--atomic` save can be rolled back.  A nil value indicates that the file
+// did not exist.
+type targetFileSnapshot map[string][]byte
+
+// Records the current contents of a target's configuration files.
+func snapshotTargetFiles(t *target.Target) (targetFileSnapshot, error) {
+	snap := targetFileSnapshot{}
+	for _, path := range []string{
+		t.Package().PkgYamlPath(),
+		t.TargetYamlPath(),
+		t.Package().SyscfgYamlPath(),
+	} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return nil, util.ChildNewtError(err)
+			}
+			data = nil
+		}
+		snap[path] = data
+	}
+
+	return snap, nil
+}
+
+// Restores the files recorded in a snapshot.  Files that did not exist when
+// the snapshot was taken are removed.
+func (snap targetFileSnapshot) restore() error {
+	for path, data := range snap {
+		if data == nil {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return util.ChildNewtError(err)
+			}
+		} else if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return util.ChildNewtError(err)
+		}
+	}
+
+	return nil
+}
+
+// Saves each of the specified targets.  If any save fails, the configuration
+// files of every target are restored to their state before the call.
+func saveTargetsAtomic(targets []*target.Target) error {
+	snaps := []targetFileSnapshot{}
+	for _, t := range targets {
+		snap, err := snapshotTargetFiles(t)
+		if err != nil {
+			return err
+		}
+		snaps = append(snaps, snap)
+	}
+
+	for _, t := range targets {
+		if err := t.Save(); err != nil {
+			for _, snap := range snaps {
+				if rerr := snap.restore(); rerr != nil {
+					util.OneTimeWarningError(rerr)
+				}
+			}
+			return util.PreNewtError(err,
+				"Failed to save target %s; no targets modified",
+				t.FullName())
+		}
+	}
+
+	return nil
+}
+
+// Reads set or amend specifications from stdin, one per line, and applies
+// them with the specified function.  Each line contains the arguments that
+// would otherwise be passed on the command line; blank lines and lines
+// starting with '#' are ignored.  Every modified target is saved once, after
+// all lines have been applied.
+func targetBatchCmd(cmd *cobra.Command, args []string,
+	applyFn func(*cobra.Command, []string) (*target.Target, []string)) {
+
+	if len(args) != 0 {
+		NewtUsage(cmd, util.NewNewtError(
+			"--batch reads its arguments from stdin; no arguments allowed"))
+	}
+
+	var targets []*target.Target
+	var msgs []string
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineArgs, err := shellquote.Split(line)
+		if err != nil {
+			NewtUsage(nil, util.FmtNewtError(
+				"stdin line %d: %s", lineNum, err.Error()))
+		}
+		if len(lineArgs) < 2 {
+			NewtUsage(nil, util.FmtNewtError(
+				"stdin line %d: must specify a target name and at least "+
+					"one variable", lineNum))
+		}
+
+		t, lineMsgs := applyFn(cmd, lineArgs)
+		if t == nil {
+			continue
+		}
+
+		found := false
+		for _, other := range targets {
+			if other == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			targets = append(targets, t)
+		}
+		msgs = append(msgs, lineMsgs...)
+	}
+	if err := scanner.Err(); err != nil {
+		NewtUsage(nil, util.ChildNewtError(err))
+	}
+
+	// Every line has been applied in memory; nothing has been written yet.
+	if targetAtomic {
+		if err := saveTargetsAtomic(targets); err != nil {
+			NewtUsage(nil, err)
+		}
+	} else {
+		for _, t := range targets {
+			if err := t.Save(); err != nil {
+				NewtUsage(nil, err)
+			}
+		}
+	}
+
+	for _, msg := range msgs {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", msg)
+	}
+}
+
+func targetSetCmd(cmd *cobra.Command, args []string) {
+	TryGetProject()
+
+	if targetAtomic && !targetBatch {
+		NewtUsage(cmd, util.NewNewtError("--atomic requires --batch"))
+	}
+
+	if targetBatch && setNoSave {
+		NewtUsage(cmd, util.NewNewtError(
+			"--no-save cannot be used with --batch"))
+	}
+
+	if targetBatch {
+		targetBatchCmd(cmd, args, targetSetApply)
+		return
+	}
+
+	if len(args) < 2 {
+		NewtUsage(cmd,
+			util.NewNewtError("Must specify at least two arguments "+
+				"(target-name & k=v) to set"))
+	}
+
+	t, msgs := targetSetApply(cmd, args)
+	if t == nil {
+		return
+	}
+
+	if setNoSave {
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"Target %s not saved (--no-save); resulting configuration:\n",
+			t.FullName())
+		targetShowVars(t)
+		return
+	}
+
+	if err := t.Save(); err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	for _, msg := range msgs {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", msg)
+	}
+}
+
+// Removes the settings that a target already sets from a syscfg assignment
+// ("A=1:B=2") and displays a message for each one.  Returns the remaining
+// settings in the same form, or "" if the target sets all of them.
+func unsetSyscfgValue(t *target.Target, value string) string {
+	vals, err := syscfg.KeyValueFromStr(value)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	curVals, err := t.Package().SyscfgY.GetValStringMapString(
+		"syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+
+	names := make([]string, 0, len(vals))
+	for name, _ := range vals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := curVals[name]; ok {
+			util.StatusMessage(util.VERBOSITY_DEFAULT,
+				"Target %s already sets syscfg %s; skipping\n",
+				t.FullName(), name)
+			delete(vals, name)
+		}
+	}
+
+	if len(vals) == 0 {
+		return ""
+	}
+
+	return syscfg.KeyValueToStr(vals)
+}
+
+// Applies a set specification (target name followed by k=v pairs) to a target
+// without saving it.  Returns the modified target and the messages to display
+// once it has been saved.  A nil target indicates that nothing was changed.
+func targetSetApply(cmd *cobra.Command, args []string) (
+	*target.Target, []string) {
+
+	// Parse target name.
+	t, err := resolveExistingTargetArg(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	if err := checkTargetUnlocked(t); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	// Parse series of k=v pairs.  If an argument doesn't contain a '='
+	// character, display the valid values for the variable and quit.
+	vars := [][]string{}
+	for i := 1; i < len(args); i++ {
+		kv := strings.SplitN(args[i], "=", 2)
+		key := strings.TrimPrefix(kv[0], "target.")
+
+		// "<flags-var>-=<flags>" removes individual flags from a build flag
+		// variable.  The "-" suffix is retained in kv[0] to mark the removal.
+		remove := false
+		if strings.HasSuffix(key, "-") &&
+			isBuildFlagVar(strings.TrimSuffix(key, "-")) {
+
+			key = strings.TrimSuffix(key, "-")
+			remove = true
+		}
+
+		supported := false
+		for _, v := range setVars {
+			if key == v {
+				supported = true
+				break
+			}
+		}
+
+		if !supported {
+			// Custom variables (e.g., read by a BSP's scripts) are only
+			// accepted if the user explicitly allows them.
+			if !setAllowUnknown || key == "" || strings.ContainsAny(key, " \t") {
+				NewtUsage(cmd,
+					util.NewNewtError("Not a valid variable: "+key))
+			}
+		}
+		if !strings.HasPrefix(kv[0], "target.") {
+			kv[0] = "target." + kv[0]
+		}
+
+		// Make sure it is a valid variable.
+
+		if len(kv) == 1 {
+			// User entered a variable name without a value.
+			NewtUsage(cmd, nil)
+		}
+
+		if remove && strings.TrimSpace(kv[1]) == "" {
+			NewtUsage(cmd, util.FmtNewtError(
+				"Must specify the flags to remove from %s", key))
+		}
+
+		if isBuildFlagVar(key) {
+			kv[1] = normalizeBuildFlagValue(key, kv[1])
+			if flagExpandHome {
+				kv[1], err = expandHomeInFlagValue(key, kv[1])
+				if err != nil {
+					NewtUsage(nil, err)
+				}
+			}
+		}
+
+		// Trim trailing slash from value.  This is necessary when tab
+		// completion is used to fill in the value.
+		kv[1] = strings.TrimSuffix(kv[1], "/")
+
+		vars = append(vars, kv)
+	}
+
+	// If requested, skip variables that already have a value.  Syscfg
+	// settings are considered individually.
+	if setIfUnset {
+		curVals := t.ToMap()
+
+		unsetVars := [][]string{}
+		for _, kv := range vars {
+			key := strings.TrimPrefix(kv[0], "target.")
+			if key == "syscfg" {
+				if v := unsetSyscfgValue(t, kv[1]); v != "" {
+					unsetVars = append(unsetVars, []string{kv[0], v})
+				}
+			} else if curVals[key] != "" {
+				util.StatusMessage(util.VERBOSITY_DEFAULT,
+					"Target %s already sets %s; skipping\n",
+					t.FullName(), key)
+			} else {
+				unsetVars = append(unsetVars, kv)
+			}
+		}
+		vars = unsetVars
+
+		if len(vars) == 0 {
+			return nil, nil
+		}
+	}
+
+	msgs := []string{}
+
+	// Set each specified variable in the target.
+	for _, kv := range vars {
+		// A few variables are special cases; they get set in the base package
+		// instead of the target.
+		if strings.HasSuffix(kv[0], "-") {
+			name := strings.TrimSuffix(strings.TrimPrefix(kv[0], "target."),
+				"-")
+			pkgVar := "pkg." + name
+
+			curFlags, err := t.Package().PkgY.GetValStringSlice(pkgVar, nil)
+			util.OneTimeWarningError(err)
+
+			newFlags, removed := removeBuildFlags(curFlags,
+				strings.Fields(kv[1]), false)
+			if len(newFlags) == 0 {
+				t.Package().PkgY.Replace(pkgVar, nil)
+			} else {
+				t.Package().PkgY.Replace(pkgVar, newFlags)
+			}
+
+			if removed == 0 {
+				msgs = append(msgs, fmt.Sprintf(
+					"Target %s %s does not contain %s; nothing removed",
+					t.FullName(), name, kv[1]))
+			} else {
+				msgs = append(msgs, fmt.Sprintf(
+					"Target %s successfully removed %s from %s",
+					t.FullName(), kv[1], name))
+			}
+		} else if kv[0] == "target.syscfg" {
+			kv, err := syscfg.KeyValueFromStr(kv[1])
+			if err != nil {
+				NewtUsage(cmd, err)
+			}
+
+			if err := checkSyscfgTypes(t, kv); err != nil {
+				NewtUsage(nil, err)
+			}
+
+			if setIfUnset {
+				// Only settings the target doesn't set remain; keep the
+				// existing ones.
+				mergeSysCfg(t, kv, false)
+			} else {
+				t.Package().SyscfgY.Clear()
+				itfMap := util.StringMapStringToItfMapItf(kv)
+				t.Package().SyscfgY.Replace("syscfg.vals", itfMap)
+			}
+		} else if kv[0] == "target.cflags" ||
+			kv[0] == "target.cxxflags" ||
+			kv[0] == "target.lflags" ||
+			kv[0] == "target.aflags" {
+
+			kv[0] = "pkg." + strings.TrimPrefix(kv[0], "target.")
+			if kv[1] == "" {
+				// User specified empty value; delete variable.
+				t.Package().PkgY.Replace(kv[0], nil)
+			} else {
+				t.Package().PkgY.Replace(kv[0], strings.Fields(kv[1]))
+			}
+		} else {
+			if kv[1] == "" {
+				// User specified empty value; delete variable.
+				t.TargetY.Delete(kv[0])
+			} else {
+				// Assign value to specified variable.
+				t.TargetY.Replace(kv[0], kv[1])
+			}
+		}
+	}
+
+	// Refresh the target's variables so that they reflect the new settings.
+	t.LoadVars()
+
+	for _, kv := range vars {
+		if strings.HasSuffix(kv[0], "-") {
+			// Message already generated above.
+		} else if kv[1] == "" {
+			msgs = append(msgs, fmt.Sprintf(
+				"Target %s successfully unset %s", t.FullName(), kv[0]))
+		} else {
+			msgs = append(msgs, fmt.Sprintf(
+				"Target %s successfully set %s to %s", t.FullName(), kv[0],
+				kv[1]))
+		}
+	}
+
+	return t, msgs
+}
+
+func targetAmendCmd(cmd *cobra.Command, args []string) {
+	if amendPrefix && !amendDelete {
+		NewtUsage(cmd, util.NewNewtError("--prefix requires --delete"))
+	}
+
+	TryGetProject()
+
+	multi := len(amendTargets) > 0 ||
+		(len(args) > 0 && strings.ContainsAny(args[0], "*?["))
+	if targetAtomic && !targetBatch && !multi {
+		NewtUsage(cmd, util.NewNewtError(
+			"--atomic requires --batch, --targets, or a target name "+
+				"pattern"))
+	}
+
+	if targetBatch && len(amendTargets) > 0 {
+		NewtUsage(cmd, util.NewNewtError(
+			"--targets cannot be used with --batch"))
+	}
+
+	if targetBatch {
+		targetBatchCmd(cmd, args, targetAmendApply)
+		return
+	}
+
+	if len(amendTargets) > 0 {
+		if len(args) < 1 {
+			NewtUsage(cmd, util.NewNewtError(
+				"Must specify at least one variable=value to append"))
+		}
+		targetAmendMultiCmd(cmd, amendTargets, args)
+		return
+	}
+
+	if len(args) < 2 {
+		NewtUsage(cmd,
+			util.NewNewtError("Must specify at least two arguments "+
+				"(target-name & variable=value) to append"))
+	}
+
+	if strings.ContainsAny(args[0], "*?[") {
+		targetAmendMultiCmd(cmd, args[:1], args[1:])
+		return
+	}
+
+	t, msgs := targetAmendApply(cmd, args)
+
+	if err := t.Save(); err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	for _, msg := range msgs {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", msg)
+	}
+}
+
+// Applies the same amend specification to every target matching the specified
+// selectors, saving each target individually.  A failure to amend one target
+// does not prevent the others from being amended; the outcome for each target
+// is reported.  With --atomic, the targets are only saved if all of them were
+// amended, and a failed save restores every target.
+func targetAmendMultiCmd(cmd *cobra.Command, selectors []string,
+	varArgs []string) {
+
+	targets, err := resolveTargetSelectors(selectors)
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	vars := parseAmendVars(cmd, varArgs)
+
+	// With --atomic, every target is amended in memory first and nothing is
+	// saved unless all of them succeed.
+	var staged []*target.Target
+	var stagedMsgs []string
+
+	failures := 0
+	for _, t := range targets {
+		err := checkTargetUnlocked(t)
+		var msgs []string
+		if err == nil {
+			msgs, err = amendTarget(t, vars)
+		}
+		if err == nil && !targetAtomic {
+			err = t.Save()
+		}
+
+		if err != nil {
+			failures++
+			util.ErrorMessage(util.VERBOSITY_QUIET,
+				"Error: failed to amend target %s: %s\n",
+				t.FullName(), err.Error())
+			continue
+		}
+
+		if targetAtomic {
+			staged = append(staged, t)
+			stagedMsgs = append(stagedMsgs, msgs...)
+			continue
+		}
+
+		for _, msg := range msgs {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", msg)
+		}
+	}
+
+	if failures > 0 {
+		if targetAtomic {
+			NewtUsage(nil, util.FmtNewtError(
+				"Failed to amend %d of %d targets; no targets modified",
+				failures, len(targets)))
+		}
+		NewtUsage(nil, util.FmtNewtError(
+			"Failed to amend %d of %d targets", failures, len(targets)))
+	}
+
+	if targetAtomic {
+		if err := saveTargetsAtomic(staged); err != nil {
+			NewtUsage(nil, err)
+		}
+
+		for _, msg := range stagedMsgs {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", msg)
+		}
+	}
+}
+
+// Applies an amend specification (target name followed by variable=value
+// pairs) to a target without saving it.  Returns the modified target and the
+// messages to display once it has been saved.
+func targetAmendApply(cmd *cobra.Command, args []string) (
+	*target.Target, []string) {
+
+	// Parse target name.
+	t, err := resolveExistingTargetArg(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	if err := checkTargetUnlocked(t); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	vars := parseAmendVars(cmd, args[1:])
+
+	msgs, err := amendTarget(t, vars)
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	return t, msgs
+}
+
+// Parses a series of variable=value amend arguments into name-value pairs.  If
+// an argument doesn't contain a '=' character, the valid values for the
+// variable are displayed and newt quits.
+func parseAmendVars(cmd *cobra.Command, args []string) [][]string {
+	var err error
+
+	vars := [][]string{}
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		// Check that the variable can have values appended.
+		valid := false
+		for _, v := range amendVars {
+			if kv[0] == v {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			NewtUsage(cmd,
+				util.NewNewtError("Cannot amend values for "+kv[0]))
+		}
+
+		if len(kv) == 1 {
+			// User entered a variable name without a '='
+			NewtUsage(cmd, nil)
+		}
+		kv[1] = strings.TrimSpace(kv[1])
+		if isBuildFlagVar(kv[0]) {
+			kv[1] = normalizeBuildFlagValue(kv[0], kv[1])
+			if flagExpandHome {
+				kv[1], err = expandHomeInFlagValue(kv[0], kv[1])
+				if err != nil {
+					NewtUsage(nil, err)
+				}
+			}
+		}
+		if kv[1] == "" {
+			NewtUsage(cmd,
+				util.NewNewtError("Must provide a value to"+
+					" append for variable "+kv[0]))
+
+		}
+		// Trim trailing slash from value.  This is necessary when tab
+		// completion is used to fill in the value.
+		kv[1] = strings.TrimSuffix(kv[1], "/")
+		vars = append(vars, kv)
+	}
+
+	return vars
+}
+
+// Applies parsed amend name-value pairs to a target without saving it.
+// Returns the messages to display once the target has been saved.
+func amendTarget(t *target.Target, vars [][]string) ([]string, error) {
+	removedCounts := map[string]int{}
+	syscfgChanged := 0
+	for _, kv := range vars {
+		if kv[0] == "syscfg" {
+			changed, err := amendSysCfg(kv[1], t)
+			if err != nil {
+				return nil, err
+			}
+			syscfgChanged += changed
+		} else if kv[0] == "cflags" ||
+			kv[0] == "cxxflags" ||
+			kv[0] == "lflags" ||
+			kv[0] == "aflags" {
+			removed, err := amendBuildFlags(kv, t)
+			if err != nil {
+				return nil, err
+			}
+			removedCounts[kv[0]] += removed
+		}
+	}
+
+	msgs := []string{}
+	for _, kv := range vars {
+		if kv[0] == "syscfg" && syscfgChanged == 0 {
+			// Deletions of absent settings have already been reported.
+			if !amendDelete {
+				msgs = append(msgs, fmt.Sprintf(
+					"Target %s syscfg is unchanged", t.FullName()))
+			}
+			continue
+		}
+		if amendDelete && isBuildFlagVar(kv[0]) {
+			msgs = append(msgs, fmt.Sprintf(
+				"Removed %d flag(s) from %s", removedCounts[kv[0]], kv[0]))
+		}
+		msgs = append(msgs, fmt.Sprintf(
+			"Amended %s for Target %s successfully", kv[0], t.FullName()))
+	}
+
+	return msgs, nil
+}
+
+func targetImportSyscfgCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		NewtUsage(cmd, util.NewNewtError("Must specify exactly one "+
+			"target and one settings file"))
+	}
+
+	TryGetProject()
+
+	t, err := resolveExistingTargetArg(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	if err := checkTargetUnlocked(t); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	vals, err := syscfg.KeyValueFromFile(args[1])
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	if err := checkSyscfgTypes(t, vals); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	if importReplace {
+		t.Package().SyscfgY.Clear()
+	}
+	mergeSysCfg(t, vals, false)
+
+	if err := t.SaveSyscfg(); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		"Imported %d syscfg setting(s) from %s into target %s\n",
+		len(vals), args[1], t.FullName())
+}
+
+// Ensures a new package would not overwrite an existing one.  An error is
+// returned if a package with the specified name exists in the local repo, or
+// if a package file is already present in the package's directory.
+func checkNewPackageCollision(pkgName string) error {
+	proj := TryGetProject()
+	repo := proj.LocalRepo()
+
+	if lpkg, err := proj.ResolvePackage(repo, pkgName); err == nil {
+		return util.FmtNewtError(
+			"Package already exists: %s (type=%s); use -f to overwrite",
+			lpkg.FullName(), pkg.PackageTypeNames[lpkg.Type()])
+	}
+
+	path := repo.Path() + "/" + pkgName + "/" + pkg.PACKAGE_FILE_NAME
+	if util.NodeExist(path) {
+		return util.FmtNewtError(
+			"Package file already exists: %s; use -f to overwrite", path)
+	}
+
+	return nil
+}
+
+func targetCreateCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 1 {
+		NewtUsage(cmd, util.NewNewtError("Missing target name"))
+	}
+
+	proj := TryGetProject()
+
+	pkgName, err := ResolveNewTargetName(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	if !newtutil.NewtForce {
+		if err := checkNewPackageCollision(pkgName); err != nil {
+			NewtUsage(nil, err)
+		}
+	}
+
+	if createCopyFrom != "" {
+		srcTarget, err := resolveExistingTargetArg(createCopyFrom)
+		if err != nil {
+			NewtUsage(cmd, err)
+		}
+
+		if _, err := targetCopyOne(srcTarget, pkgName); err != nil {
+			NewtUsage(nil, err)
+		}
+
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"Target %s successfully created from %s\n", pkgName,
+			srcTarget.FullName())
+		return
+	}
+
+	repo := proj.LocalRepo()
+	pack := pkg.NewLocalPackage(repo, repo.Path()+"/"+pkgName)
+	pack.SetName(pkgName)
+	pack.SetType(pkg.PACKAGE_TYPE_TARGET)
+
+	t := target.NewTarget(pack)
+	err = t.Save()
+	if err != nil {
+		NewtUsage(nil, err)
+	} else {
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"Target %s successfully created\n", pkgName)
+	}
+}
+
+func targetDelOne(t *target.Target) error {
+	if err := checkTargetUnlocked(t); err != nil {
+		return err
+	}
+
+	if !newtutil.NewtForce {
+		// Determine if the target directory contains extra user files.  If it
+		// does, a prompt (or force) is required to delete it.
+		userFiles, err := targetContainsUserFiles(t)
+		if err != nil {
+			return err
+		}
+
+		if userFiles {
+			dflt := DestructivePromptDefault()
+			fmt.Printf("Target directory %s contains some extra content; "+
+				"delete anyway? %s: ", t.Package().BasePath(),
+				PromptYesNoHint(dflt))
+			rsp := PromptYesNo(dflt)
+			if !rsp {
+				return nil
+			}
+		}
+	}
+
+	if err := util.RemoveAllRetry(t.Package().BasePath()); err != nil {
+		return err
+	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		"Target %s successfully deleted.\n", t.FullName())
+
+	return nil
+}
+
+// Locates a target template.  The argument is either the path of a template
+// file or the name of a template in the project's template directory.
+func resolveTargetTemplate(arg string) (string, error) {
+	if util.NodeExist(arg) {
+		return arg, nil
+	}
+
+	proj := TryGetProject()
+	path := fmt.Sprintf("%s/%s/%s.yml", proj.Path(), TARGET_TEMPLATE_DIR,
+		strings.TrimSuffix(arg, ".yml"))
+	if util.NodeNotExist(path) {
+		return "", util.FmtNewtError("Unknown target template: %s", arg)
+	}
+
+	return path, nil
+}
+
+func targetApplyCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		NewtUsage(cmd, util.NewNewtError("Must specify exactly one "+
+			"target and one template"))
+	}
+
+	TryGetProject()
+
+	path, err := resolveTargetTemplate(args[1])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	// Templates are composed with the standard `$import` directive; imported
+	// maps are merged and imported sequences are concatenated.
+	yc, err := config.ReadFile(path)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	setVals, err := yc.GetValStringMapString("template.set", nil)
+	util.OneTimeWarningError(err)
+
+	syscfgVals, err := yc.GetValStringMapString("template.syscfg", nil)
+	util.OneTimeWarningError(err)
+
+	// Convert the template into set and amend specifications.
+	setArgs := []string{args[0]}
+	keys := make([]string, 0, len(setVals))
+	for k, _ := range setVals {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		setArgs = append(setArgs, k+"="+setVals[k])
+	}
+
+	amendArgs := []string{args[0]}
+	if len(syscfgVals) > 0 {
+		amendArgs = append(amendArgs,
+			"syscfg="+syscfg.KeyValueToStr(syscfgVals))
+	}
+	for _, k := range flagVars {
+		flags, err := yc.GetValStringSlice("template."+k, nil)
+		util.OneTimeWarningError(err)
+		if len(flags) > 0 {
+			amendArgs = append(amendArgs, k+"="+strings.Join(flags, " "))
+		}
+	}
+
+	if len(setArgs) == 1 && len(amendArgs) == 1 {
+		NewtUsage(nil, util.FmtNewtError(
+			"Target template %s does not specify any settings", path))
+	}
+
+	var t *target.Target
+	var msgs []string
+	if len(setArgs) > 1 {
+		t, msgs = targetSetApply(cmd, setArgs)
+	}
+	if len(amendArgs) > 1 {
+		var amendMsgs []string
+		t, amendMsgs = targetAmendApply(cmd, amendArgs)
+		msgs = append(msgs, amendMsgs...)
+	}
+
+	if err := t.Save(); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	for _, msg := range msgs {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n", msg)
+	}
+}
+
+func targetLockCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		NewtUsage(cmd, util.NewNewtError("Must specify at least one "+
+			"target to lock"))
+	}
+
+	TryGetProject()
+
+	targets, err := ResolveTargets(args...)
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	for _, t := range targets {
+		t.SetLocked(!lockUndo)
+		if err := t.Package().Save(); err != nil {
+			NewtUsage(nil, err)
+		}
+
+		if lockUndo {
+			util.StatusMessage(util.VERBOSITY_DEFAULT,
+				"Target %s successfully unlocked.\n", t.FullName())
+		} else {
+			util.StatusMessage(util.VERBOSITY_DEFAULT,
+				"Target %s successfully locked.\n", t.FullName())
+		}
+	}
+}
+
+// Indicates whether a YAML value, or any value nested within it, is one of the
+// specified strings.
+func yamlValueRefers(v interface{}, names []string) bool {
+	switch val := v.(type) {
+	case string:
+		for _, name := range names {
+			if strings.TrimSpace(val) == name {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, elem := range val {
+			if yamlValueRefers(elem, names) {
+				return true
+			}
+		}
+	case map[interface{}]interface{}:
+		for _, elem := range val {
+			if yamlValueRefers(elem, names) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, elem := range val {
+			if yamlValueRefers(elem, names) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Lists the packages that refer to the specified target by name in any of
+// their YAML files (e.g., an mfg package that includes the target).  Every
+// package in the project, including those in other repos, is scanned.
+func targetReferrers(t *target.Target) ([]string, error) {
+	proj := TryGetProject()
+
+	names := []string{
+		t.FullName(),
+		"@" + t.Package().Repo().Name() + "/" + t.Package().Name(),
+	}
+
+	referrers := []string{}
+	for _, pkgList := range proj.PackageList() {
+		for _, pack := range *pkgList {
+			lpkg := pack.(*pkg.LocalPackage)
+			if lpkg == t.Package() {
+				continue
+			}
+
+			files, err := filepath.Glob(lpkg.BasePath() + "/*.yml")
+			if err != nil {
+				return nil, util.ChildNewtError(err)
+			}
+
+			found := false
+			for _, file := range files {
+				yc, err := config.ReadFile(file)
+				if err != nil {
+					// An unreadable file has already been reported when
+					// the package was loaded.
+					continue
+				}
+
+				for _, v := range yc.AllSettings() {
+					if yamlValueRefers(v, names) {
+						found = true
+						break
+					}
+				}
+				if found {
+					break
+				}
+			}
+
+			if found {
+				referrers = append(referrers, lpkg.FullName())
+			}
+		}
+	}
+	sort.Strings(referrers)
+
+	return referrers, nil
+}
+
+func targetDelCmd(cmd *cobra.Command, args []string) {
+	if len(args) < 1 {
+		NewtUsage(cmd, util.NewNewtError("Must specify at least one "+
+			"target to delete"))
+	}
+
+	TryGetProject()
+
+	names := []string{}
+	patterns := []string{}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "*?[") {
+			patterns = append(patterns, arg)
+		} else {
+			names = append(names, arg)
+		}
+	}
+
+	// Delete every target that exists, then report any unknown names.
+	targets, unresolved := ResolveTargetsPartial(names...)
+	for _, pattern := range patterns {
+		matches, err := resolveTargetSelectors([]string{pattern})
+		if err != nil {
+			unresolved = append(unresolved, pattern)
+			continue
+		}
+
+		for _, m := range matches {
+			dup := false
+			for _, t := range targets {
+				if t == m {
+					dup = true
+					break
+				}
+			}
+			if !dup {
+				targets = append(targets, m)
+			}
+		}
+	}
+	if len(targets) == 0 {
+		NewtUsage(cmd, util.NewNewtError("Could not resolve target name: "+
+			strings.Join(unresolved, ", ")))
+	}
+
+	for _, t := range targets {
+		if delSafe {
+			refs, err := targetReferrers(t)
+			if err != nil {
+				NewtUsage(nil, err)
+			}
+			if len(refs) > 0 {
+				util.ErrorMessage(util.VERBOSITY_QUIET,
+					"Warning: not deleting target %s; it is referenced by "+
+						"%s\n", t.FullName(), strings.Join(refs, ", "))
+				continue
+			}
+		}
+
+		if err := targetDelOne(t); err != nil {
+			NewtUsage(cmd, err)
+		}
+	}
+
+	if len(unresolved) > 0 {
+		NewtUsage(nil, util.NewNewtError("Could not resolve target name: "+
+			strings.Join(unresolved, ", ")))
+	}
+}
+
+// Copies the syscfg.vals settings of one target into another and saves the
+// destination's syscfg.yml file.  If `replace` is true, the destination's
+// existing settings are discarded; otherwise the source settings are merged
+// on top of them.  Returns the number of settings copied.
+func targetCopySyscfg(srcTarget *target.Target, dstTarget *target.Target,
+	replace bool) (int, error) {
+
+	vals, err := srcTarget.Package().SyscfgY.GetValStringMapString(
+		"syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+
+	if replace {
+		dstTarget.Package().SyscfgY.Replace("syscfg.vals",
+			util.StringMapStringToItfMapItf(vals))
+	} else {
+		mergeSysCfg(dstTarget, vals, false)
+	}
+
+	if err := dstTarget.SaveSyscfg(); err != nil {
+		return 0, err
+	}
+
+	return len(vals), nil
+}
+
+// Merges the syscfg.vals settings of one target into another and saves the
+// destination's syscfg.yml file.  A setting defined by both targets keeps the
+// destination's value unless `preferSource` is true.  Returns the number of
+// settings taken from the source.
+func targetMergeSyscfg(srcTarget *target.Target, dstTarget *target.Target,
+	preferSource bool) (int, error) {
+
+	vals, err := srcTarget.Package().SyscfgY.GetValStringMapString(
+		"syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+
+	if !preferSource {
+		dstVals, err := dstTarget.Package().SyscfgY.GetValStringMapString(
+			"syscfg.vals", nil)
+		util.OneTimeWarningError(err)
+
+		for k, _ := range dstVals {
+			delete(vals, k)
+		}
+	}
+
+	mergeSysCfg(dstTarget, vals, false)
+
+	if err := dstTarget.SaveSyscfg(); err != nil {
+		return 0, err
+	}
+
+	return len(vals), nil
+}
+
+func targetCopySyscfgCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		NewtUsage(cmd, util.NewNewtError("Must specify exactly one "+
+			"source target and one destination target"))
+	}
+
+	TryGetProject()
+
+	srcTarget, err := resolveExistingTargetArg(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	dstTarget, err := resolveExistingTargetArg(args[1])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	if srcTarget == dstTarget {
+		NewtUsage(cmd, util.NewNewtError(
+			"Source and destination targets must differ"))
+	}
+
+	if err := checkTargetUnlocked(dstTarget); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	count, err := targetCopySyscfg(srcTarget, dstTarget, copySyscfgReplace)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		"Copied %d syscfg setting(s); %s --> %s\n",
+		count, srcTarget.FullName(), dstTarget.FullName())
+}
+
+// Creates a new target by cloning an existing one.  The source target's
+// syscfg.yml file is copied along with its base package.
+func targetCopyOne(srcTarget *target.Target, dstName string) (
+	*target.Target, error) {
+
+	proj := TryGetProject()
+
+	// Copy the source target's base package and adjust the fields which need
+	// to change.
+	dstTarget := srcTarget.Clone(proj.LocalRepo(), dstName)
+
+	// Save the new target.  The clone includes the source target's syscfg
+	// settings, so this writes its syscfg.yml file as well.
+	if err := dstTarget.Save(); err != nil {
+		return nil, err
+	}
+
+	return dstTarget, nil
+}
+
+// Ensures the specified target can be resolved.  An error describing the
+// failure is returned otherwise.
+func targetVerifyResolves(t *target.Target) error {
+	b, err := builder.NewTargetBuilder(t)
+	if err != nil {
+		return err
+	}
+
+	if _, err := b.Resolve(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Builds a regular expression matching pkg.yml sequence entries that name the
+// specified package, either bare or qualified with the local repo's name.  An
+// optional-dependency suffix is preserved.
+func pkgDepEntryRegexp(repoName string, pkgName string) *regexp.Regexp {
+	return regexp.MustCompile(`(?m)^([ \t]*-[ \t]*)(["']?)((?:@` +
+		regexp.QuoteMeta(repoName) + `/)?)` + regexp.QuoteMeta(pkgName) +
+		`((?:` + regexp.QuoteMeta(pkg.DEP_OPTIONAL_SUFFIX) + `)?["']?)[ \t]*$`)
+}
+
+func targetRenamePkgCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		NewtUsage(cmd, util.NewNewtError(
+			"Must specify exactly two arguments: old and new package names"))
+	}
+
+	proj := TryGetProject()
+	localRepo := proj.LocalRepo()
+
+	oldRepoName, oldName, err := newtutil.ParsePackageString(args[0])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+	newRepoName, newName, err := newtutil.ParsePackageString(args[1])
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+	if (oldRepoName != "" && oldRepoName != localRepo.Name()) ||
+		(newRepoName != "" && newRepoName != localRepo.Name()) {
+
+		NewtUsage(cmd, util.NewNewtError(
+			"Only packages in the local repo can be renamed"))
+	}
+
+	srcPkg, err := proj.ResolvePackage(localRepo, oldName)
+	if err != nil {
+		NewtUsage(cmd, err)
+	}
+
+	dstPath := localRepo.Path() + "/" + newName
+	if util.NodeExist(dstPath) {
+		NewtUsage(nil, util.FmtNewtError(
+			"Cannot overwrite existing directory: %s", dstPath))
+	}
+
+	// Find every local package whose pkg.yml refers to the old name.  The
+	// files are edited in place rather than regenerated so that fields newt
+	// does not save, as well as comments and formatting, are preserved.
+	re := pkgDepEntryRegexp(localRepo.Name(), oldName)
+	dependents := []*pkg.LocalPackage{}
+	for _, pack := range *proj.PackageList()[localRepo.Name()] {
+		lpkg := pack.(*pkg.LocalPackage)
+		if lpkg == srcPkg {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(lpkg.PkgYamlPath())
+		if err != nil {
+			NewtUsage(nil, util.ChildNewtError(err))
+		}
+		if re.Match(data) {
+			dependents = append(dependents, lpkg)
+		}
+	}
+	sort.Slice(dependents, func(i int, j int) bool {
+		return dependents[i].FullName() < dependents[j].FullName()
+	})
+
+	if !newtutil.NewtForce {
+		for _, lpkg := range dependents {
+			util.StatusMessage(util.VERBOSITY_DEFAULT,
+				"Would update %s\n", lpkg.FullName())
+		}
+		NewtUsage(nil, util.FmtNewtError(
+			"Renaming %s modifies %d other package(s); use -f to proceed",
+			srcPkg.FullName(), len(dependents)))
+	}
+
+	if err := util.MoveDir(srcPkg.BasePath(), dstPath); err != nil {
+		NewtUsage(nil, err)
 	}
 
-	proj := TryGetProject()
-
-	pkgName, err := ResolveNewTargetName(args[0])
+	// Replace the package name in the moved package's pkg.yml file.
+	pkgYmlPath := dstPath + "/" + pkg.PACKAGE_FILE_NAME
+	data, err := ioutil.ReadFile(pkgYmlPath)
 	if err != nil {
-		NewtUsage(cmd, err)
+		NewtUsage(nil, util.ChildNewtError(err))
+	}
+	nameRe := regexp.MustCompile(`(?m)^pkg\.name:.*$`)
+	data = nameRe.ReplaceAll(data,
+		[]byte("pkg.name: "+yaml.EscapeString(newName)))
+	if err := ioutil.WriteFile(pkgYmlPath, data, 0644); err != nil {
+		NewtUsage(nil, util.ChildNewtError(err))
 	}
 
-	repo := proj.LocalRepo()
-	pack := pkg.NewLocalPackage(repo, repo.Path()+"/"+pkgName)
-	pack.SetName(pkgName)
-	pack.SetType(pkg.PACKAGE_TYPE_TARGET)
-
-	t := target.NewTarget(pack)
-	err = t.Save()
-	if err != nil {
-		NewtUsage(nil, err)
-	} else {
-		util.StatusMessage(util.VERBOSITY_DEFAULT,
-			"Target %s successfully created\n", pkgName)
+	// If the last element of the package path changes, rename the include
+	// directory.
+	if path.Base(newName) != path.Base(oldName) {
+		incDir := dstPath + "/include/" + path.Base(oldName)
+		if util.NodeExist(incDir) {
+			err := util.MoveDir(incDir,
+				dstPath+"/include/"+path.Base(newName))
+			if err != nil {
+				NewtUsage(nil, err)
+			}
+		}
 	}
-}
 
-func targetDelOne(t *target.Target) error {
-	if !newtutil.NewtForce {
-		// Determine if the target directory contains extra user files.  If it
-		// does, a prompt (or force) is required to delete it.
-		userFiles, err := targetContainsUserFiles(t)
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		"Renamed package %s to %s\n", oldName, newName)
+
+	for _, lpkg := range dependents {
+		data, err := ioutil.ReadFile(lpkg.PkgYamlPath())
 		if err != nil {
-			return err
+			NewtUsage(nil, util.ChildNewtError(err))
 		}
-
-		if userFiles {
-			fmt.Printf("Target directory %s contains some extra content; "+
-				"delete anyway? (y/N): ", t.Package().BasePath())
-			rsp := PromptYesNo(false)
-			if !rsp {
-				return nil
-			}
+		data = re.ReplaceAll(data, []byte("${1}${2}${3}"+newName+"${4}"))
+		if err := ioutil.WriteFile(lpkg.PkgYamlPath(), data, 0644); err != nil {
+			NewtUsage(nil, util.ChildNewtError(err))
 		}
-	}
 
-	if err := os.RemoveAll(t.Package().BasePath()); err != nil {
-		return util.NewNewtError(err.Error())
+		util.StatusMessage(util.VERBOSITY_DEFAULT,
+			"Updated dependencies of %s\n", lpkg.FullName())
 	}
+}
 
-	util.StatusMessage(util.VERBOSITY_DEFAULT,
-		"Target %s successfully deleted.\n", t.FullName())
+// Merges the syscfg settings of a source target into an existing destination
+// target (`copy --merge-syscfg`).  With --verify, the destination's settings
+// are restored if the result does not resolve.
+func targetCopyMerge(cmd *cobra.Command, srcTarget *target.Target,
+	dstTarget *target.Target) {
 
-	return nil
-}
+	if srcTarget == dstTarget {
+		NewtUsage(cmd, util.NewNewtError(
+			"Source and destination targets must differ"))
+	}
 
-func targetDelCmd(cmd *cobra.Command, args []string) {
-	if len(args) < 1 {
-		NewtUsage(cmd, util.NewNewtError("Must specify at least one "+
-			"target to delete"))
+	if err := checkTargetUnlocked(dstTarget); err != nil {
+		NewtUsage(nil, err)
 	}
 
-	TryGetProject()
+	origSyscfg := dstTarget.Package().SyscfgY.Clone()
 
-	targets, err := ResolveTargets(args...)
+	count, err := targetMergeSyscfg(srcTarget, dstTarget, copyPreferSource)
 	if err != nil {
-		NewtUsage(cmd, err)
+		NewtUsage(nil, err)
 	}
 
-	for _, t := range targets {
-		if err := targetDelOne(t); err != nil {
-			NewtUsage(cmd, err)
+	if copyVerify {
+		if err := targetVerifyResolves(dstTarget); err != nil {
+			dstTarget.Package().SyscfgY = origSyscfg
+			if saveErr := dstTarget.SaveSyscfg(); saveErr != nil {
+				util.OneTimeWarningError(saveErr)
+			}
+			NewtUsage(nil, util.FmtNewtError(
+				"Merged target %s does not resolve; syscfg restored:\n%s",
+				dstTarget.FullName(), err.Error()))
 		}
 	}
+
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		"Merged %d syscfg setting(s); %s --> %s\n",
+		count, srcTarget.FullName(), dstTarget.FullName())
 }
 
 func targetCopyCmd(cmd *cobra.Command, args []string) {
@@ -564,40 +3176,48 @@ func targetCopyCmd(cmd *cobra.Command, args []string) {
 			"source target and one destination target"))
 	}
 
-	proj := TryGetProject()
+	if copyPreferSource && !copyMergeSyscfg {
+		NewtUsage(cmd, util.NewNewtError(
+			"--prefer-source requires --merge-syscfg"))
+	}
+
+	TryGetProject()
 
 	srcTarget, err := resolveExistingTargetArg(args[0])
 	if err != nil {
 		NewtUsage(cmd, err)
 	}
 
+	// When merging, an existing destination keeps its other variables and
+	// only receives the source's syscfg settings.  A new destination is
+	// created by a regular copy.
+	if copyMergeSyscfg {
+		if dstTarget := ResolveTarget(args[1]); dstTarget != nil {
+			targetCopyMerge(cmd, srcTarget, dstTarget)
+			return
+		}
+	}
+
 	dstName, err := ResolveNewTargetName(args[1])
 	if err != nil {
 		NewtUsage(cmd, err)
 	}
 
-	// Copy the source target's base package and adjust the fields which need
-	// to change.
-	dstTarget := srcTarget.Clone(proj.LocalRepo(), dstName)
-
-	// Save the new target.
-	err = dstTarget.Save()
+	dstTarget, err := targetCopyOne(srcTarget, dstName)
 	if err != nil {
 		NewtUsage(nil, err)
 	}
 
-	// Copy syscfg.yml file.
-	srcSyscfgPath := fmt.Sprintf("%s/%s",
-		srcTarget.Package().BasePath(),
-		pkg.SYSCFG_YAML_FILENAME)
-	dstSyscfgPath := fmt.Sprintf("%s/%s",
-		dstTarget.Package().BasePath(),
-		pkg.SYSCFG_YAML_FILENAME)
-
-	if err := util.CopyFile(srcSyscfgPath, dstSyscfgPath); err != nil {
-		// If there is just no source syscfg.yml file, that is not an error.
-		if !util.IsNotExist(err) {
-			NewtUsage(nil, err)
+	if copyVerify {
+		if err := targetVerifyResolves(dstTarget); err != nil {
+			// Roll back the copy.
+			dstPath := dstTarget.Package().BasePath()
+			if rmErr := os.RemoveAll(dstPath); rmErr != nil {
+				util.OneTimeWarningError(util.ChildNewtError(rmErr))
+			}
+			NewtUsage(nil, util.FmtNewtError(
+				"Copied target %s does not resolve; copy removed:\n%s",
+				dstTarget.FullName(), err.Error()))
 		}
 	}
 
@@ -606,7 +3226,54 @@ func targetCopyCmd(cmd *cobra.Command, args []string) {
 		srcTarget.FullName(), dstTarget.FullName())
 }
 
-func targetDepCommonCmd(cmd *cobra.Command, args []string) builder.DepGraph {
+// Displays a warning encountered while resolving a dependency graph and
+// records it for --warnings-as-errors.
+func depWarning(format string, args ...interface{}) {
+	util.ErrorMessage(util.VERBOSITY_QUIET, "Warning: "+format+"\n", args...)
+	depWarningCount++
+}
+
+// Fails if --warnings-as-errors was specified and any warnings were emitted
+// while resolving a dependency graph.
+func checkDepWarnings() {
+	if depWarningsAsErrors && depWarningCount > 0 {
+		NewtUsage(nil, util.FmtNewtError(
+			"%d resolution warning(s) treated as errors", depWarningCount))
+	}
+}
+
+// Removes the packages matching the user's --exclude patterns from a
+// dependency graph.
+func excludeFromDepGraph(dg builder.DepGraph) builder.DepGraph {
+	if len(depExclude) == 0 {
+		return dg
+	}
+
+	dg, unmatched, err := builder.ExcludeDepGraph(dg, depExclude)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	for _, pattern := range unmatched {
+		depWarning("exclude pattern \"%s\" does not match any package",
+			pattern)
+	}
+
+	return dg
+}
+
+// Reports the time elapsed since `start` for the named phase of a dep command.
+// No-op unless --timings was specified.
+func reportDepTiming(phase string, start time.Time) {
+	if depTimings {
+		util.ErrorMessage(util.VERBOSITY_QUIET, "Timing: %-16s %s\n",
+			phase, time.Since(start))
+	}
+}
+
+func targetDepCommonCmd(cmd *cobra.Command, args []string) (
+	builder.DepGraph, *builder.TargetBuilder) {
+
 	if len(args) < 1 {
 		NewtUsage(cmd,
 			util.NewNewtError("Must specify target or unittest name"))
@@ -619,19 +3286,23 @@ func targetDepCommonCmd(cmd *cobra.Command, args []string) builder.DepGraph {
 		NewtUsage(cmd, err)
 	}
 
-	res, err := b.Resolve()
+	start := time.Now()
+	closure, err := b.DependencyClosure()
 	if err != nil {
 		NewtUsage(nil, err)
 	}
+	reportDepTiming("resolve", start)
 
+	start = time.Now()
 	dg, err := b.CreateDepGraph()
 	if err != nil {
 		NewtUsage(nil, err)
 	}
+	reportDepTiming("graph", start)
 
 	// If user specified any package names, only include specified packages.
 	if len(args) > 1 {
-		rpkgs, err := ResolveRpkgs(res, args[1:])
+		rpkgs, err := ResolveRpkgs(closure, args[1:])
 		if err != nil {
 			NewtUsage(cmd, err)
 		}
@@ -639,32 +3310,236 @@ func targetDepCommonCmd(cmd *cobra.Command, args []string) builder.DepGraph {
 		var missingRpkgs []*resolve.ResolvePackage
 		dg, missingRpkgs = builder.FilterDepGraph(dg, rpkgs)
 		for _, rpkg := range missingRpkgs {
-			util.StatusMessage(util.VERBOSITY_QUIET,
-				"Warning: Package \"%s\" not included in target \"%s\"\n",
+			depWarning("Package \"%s\" not included in target \"%s\"",
 				rpkg.Lpkg.FullName(), b.GetTarget().FullName())
 		}
 	}
 
-	return dg
+	dg = excludeFromDepGraph(dg)
+
+	// If user specified a root package, only include its subtree.
+	if depRoot != "" {
+		rpkgs, err := ResolveRpkgs(closure, []string{depRoot})
+		if err != nil {
+			NewtUsage(cmd, err)
+		}
+
+		rootName := rpkgs[0].Lpkg.FullName()
+		if dg[rootName] == nil {
+			NewtUsage(nil, util.FmtNewtError(
+				"Package \"%s\" not included in graph", rootName))
+		}
+		dg = builder.SubtreeDepGraph(dg, rootName, depDepth)
+	}
+
+	checkDepWarnings()
+
+	return dg, b
+}
+
+// Determines which packages to use as the roots of a dependency tree.  The
+// tree is rooted at the package specified with `--root`, or else at the
+// target's app (or the package under test).  If that package isn't in the
+// graph (e.g., the graph has been filtered), every parent in the graph is a
+// root.
+func depTreeRoots(dg builder.DepGraph, b *builder.TargetBuilder) []string {
+	var rootPkg *pkg.LocalPackage
+	if depRoot != "" {
+		if lpkgs, err := ResolvePackages([]string{depRoot}); err == nil {
+			rootPkg = lpkgs[0]
+		}
+	} else {
+		rootPkg = b.GetTestPkg()
+		if rootPkg == nil {
+			rootPkg = b.GetTarget().App()
+		}
+	}
+
+	if rootPkg != nil {
+		if _, ok := dg[rootPkg.FullName()]; ok {
+			return []string{rootPkg.FullName()}
+		}
+	}
+
+	roots := make([]string, 0, len(dg))
+	for pname, _ := range dg {
+		roots = append(roots, pname)
+	}
+	sort.Strings(roots)
+
+	return roots
+}
+
+// Ensures the --format option names a supported graph format.
+func checkDepFormat(cmd *cobra.Command) {
+	for _, f := range builder.DepGraphFormats {
+		if depFormat == f {
+			return
+		}
+	}
+
+	NewtUsage(cmd, util.FmtNewtError(
+		"Invalid --format value: %s; must be one of: %s",
+		depFormat, strings.Join(builder.DepGraphFormats, ", ")))
+}
+
+// Displays a dependency or reverse-dependency graph in the --format format.
+// Text output is a status message; other formats are data and are always
+// written to stdout.
+func printDepGraph(dg builder.DepGraph, reverse bool) {
+	renderFn := builder.DepGraphRender
+	if reverse {
+		renderFn = builder.RevdepGraphRender
+	}
+
+	buf := &bytes.Buffer{}
+	if err := renderFn(dg, depFormat, buf); err != nil {
+		NewtUsage(nil, err)
+	}
+
+	if depFormat == builder.DEP_GRAPH_FORMAT_TEXT {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s", buf.String())
+	} else {
+		fmt.Print(buf.String())
+	}
 }
 
 func targetDepCmd(cmd *cobra.Command, args []string) {
-	dg := targetDepCommonCmd(cmd, args)
+	checkDepFormat(cmd)
 
-	if len(dg) > 0 {
-		util.StatusMessage(util.VERBOSITY_DEFAULT,
-			builder.DepGraphText(dg)+"\n")
+	if depTree && depFormat != builder.DEP_GRAPH_FORMAT_TEXT {
+		NewtUsage(cmd, util.NewNewtError("--tree requires --format=text"))
+	}
+	if depLongest && (depFormat != builder.DEP_GRAPH_FORMAT_TEXT ||
+		depTree || depvizCombined) {
+
+		NewtUsage(cmd, util.NewNewtError(
+			"--longest requires --format=text and cannot be used with "+
+				"--tree or --combined"))
+	}
+	if depvizCombined && depFormat != builder.DEP_GRAPH_FORMAT_DOT {
+		NewtUsage(cmd, util.NewNewtError("--combined requires --format=dot"))
+	}
+	if depFlat && depFormat != builder.DEP_GRAPH_FORMAT_JSON {
+		NewtUsage(cmd, util.NewNewtError("--flat requires --format=json"))
+	}
+	if depvizFocus != "" && (depRoot != "" || depvizCombined) {
+		NewtUsage(cmd, util.NewNewtError(
+			"--focus cannot be used with --root or --combined"))
+	}
+
+	if depReverse {
+		if depTree || depLongest || depFlat || depRoot != "" ||
+			depvizCombined || depvizFocus != "" {
+
+			NewtUsage(cmd, util.NewNewtError(
+				"--reverse cannot be used with --tree, --longest, --flat, "+
+					"--root, --combined, or --focus"))
+		}
+
+		rdg := targetRevdepCommonCmd(cmd, args)
+		start := time.Now()
+		if len(rdg) > 0 {
+			printDepGraph(rdg, true)
+		}
+		reportDepTiming("render", start)
+		return
+	}
+
+	dg, b := targetDepCommonCmd(cmd, args)
+
+	// If user specified a focus package, only include the packages it
+	// depends on and the packages that depend on it.
+	if depvizFocus != "" {
+		lpkgs, err := ResolvePackages([]string{depvizFocus})
+		if err != nil {
+			NewtUsage(cmd, err)
+		}
+
+		focusName := lpkgs[0].FullName()
+		if dg[focusName] == nil {
+			NewtUsage(nil, util.FmtNewtError(
+				"Package \"%s\" not included in graph", focusName))
+		}
+		dg = builder.FocusDepGraph(dg, focusName)
+	}
+
+	start := time.Now()
+	if depFlat {
+		printDepInventory(dg, b)
+	} else if len(dg) > 0 {
+		if depTree {
+			util.StatusMessage(util.VERBOSITY_DEFAULT,
+				builder.DepGraphTree(dg, depTreeRoots(dg, b))+"\n")
+		} else if depLongest {
+			printLongestDepPath(dg)
+		} else if depvizCombined {
+			printCombinedGraph(dg, b)
+		} else {
+			printDepGraph(dg, false)
+		}
 	}
+	reportDepTiming("render", start)
 }
 
-func targetDepvizCmd(cmd *cobra.Command, args []string) {
-	dg := targetDepCommonCmd(cmd, args)
+// Displays the longest dependency chain in the specified graph, one package
+// per line, starting with the outermost depender.
+func printLongestDepPath(dg builder.DepGraph) {
+	path := builder.DepGraphLongestPath(dg)
 
-	if len(dg) > 0 {
-		fmt.Print(builder.DepGraphViz(dg))
+	util.StatusMessage(util.VERBOSITY_DEFAULT,
+		"Longest dependency chain (%d packages):\n", len(path))
+	for i, pname := range path {
+		prefix := "    "
+		if i > 0 {
+			prefix = "    --> "
+		}
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s%s\n", prefix, pname)
 	}
 }
 
+// Displays a flat JSON list of the packages in the specified graph, along with
+// each package's type, repo, and repo version.
+func printDepInventory(dg builder.DepGraph, b *builder.TargetBuilder) {
+	rpkgs, err := b.DependencyClosure()
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	s, err := builder.DepGraphInventoryJson(dg, rpkgs)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	fmt.Print(s)
+}
+
+// Displays a DOT graph containing both the dependencies and the reverse
+// dependencies of the packages in the specified graph.
+func printCombinedGraph(dg builder.DepGraph, b *builder.TargetBuilder) {
+	// Only include the reverse dependencies of packages in the (possibly
+	// filtered) forward graph.
+	fullRdg, err := b.CreateRevdepGraph()
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	rdg := builder.DepGraph{}
+	for pname, _ := range dg {
+		if fullRdg[pname] != nil {
+			rdg[pname] = fullRdg[pname]
+		}
+	}
+
+	fmt.Print(builder.CombinedGraphViz(dg, rdg))
+}
+
+// Equivalent to `dep --format=dot`.
+func targetDepvizCmd(cmd *cobra.Command, args []string) {
+	depFormat = builder.DEP_GRAPH_FORMAT_DOT
+	targetDepCmd(cmd, args)
+}
+
 func targetRevdepCommonCmd(cmd *cobra.Command, args []string) builder.DepGraph {
 	if len(args) < 1 {
 		NewtUsage(cmd, util.NewNewtError("Must specify target name"))
@@ -677,19 +3552,23 @@ func targetRevdepCommonCmd(cmd *cobra.Command, args []string) builder.DepGraph {
 		NewtUsage(cmd, err)
 	}
 
-	res, err := b.Resolve()
+	start := time.Now()
+	closure, err := b.DependencyClosure()
 	if err != nil {
 		NewtUsage(nil, err)
 	}
+	reportDepTiming("resolve", start)
 
+	start = time.Now()
 	dg, err := b.CreateRevdepGraph()
 	if err != nil {
 		NewtUsage(nil, err)
 	}
+	reportDepTiming("graph", start)
 
 	// If user specified any package names, only include specified packages.
 	if len(args) > 1 {
-		rpkgs, err := ResolveRpkgs(res, args[1:])
+		rpkgs, err := ResolveRpkgs(closure, args[1:])
 		if err != nil {
 			NewtUsage(cmd, err)
 		}
@@ -697,34 +3576,151 @@ func targetRevdepCommonCmd(cmd *cobra.Command, args []string) builder.DepGraph {
 		var missingRpkgs []*resolve.ResolvePackage
 		dg, missingRpkgs = builder.FilterDepGraph(dg, rpkgs)
 		for _, rpkg := range missingRpkgs {
-			util.StatusMessage(util.VERBOSITY_QUIET,
-				"Warning: Package \"%s\" not included in target \"%s\"\n",
+			depWarning("Package \"%s\" not included in target \"%s\"",
 				rpkg.Lpkg.FullName(), b.GetTarget().FullName())
 		}
 	}
 
+	dg = excludeFromDepGraph(dg)
+
+	// If user specified a root package, only include the packages that
+	// depend on it, directly or indirectly.
+	if depRoot != "" {
+		rpkgs, err := ResolveRpkgs(closure, []string{depRoot})
+		if err != nil {
+			NewtUsage(cmd, err)
+		}
+
+		rootName := rpkgs[0].Lpkg.FullName()
+		if dg[rootName] == nil {
+			NewtUsage(nil, util.FmtNewtError(
+				"Package \"%s\" not included in graph", rootName))
+		}
+		dg = builder.SubtreeDepGraph(dg, rootName, depDepth)
+	}
+
+	checkDepWarnings()
+
 	return dg
 }
 
 func targetRevdepCmd(cmd *cobra.Command, args []string) {
+	checkDepFormat(cmd)
+
+	if revdepDirect && len(args) < 2 {
+		NewtUsage(cmd, util.NewNewtError(
+			"--direct requires at least one package name"))
+	}
+	if revdepDirect && depRoot != "" {
+		NewtUsage(cmd, util.NewNewtError(
+			"--direct cannot be used with --root"))
+	}
+
 	dg := targetRevdepCommonCmd(cmd, args)
 
+	// With --root, display text output as a tree of the packages that
+	// ultimately depend on the root.
+	if depRoot != "" && depFormat == builder.DEP_GRAPH_FORMAT_TEXT {
+		lpkgs, err := ResolvePackages([]string{depRoot})
+		if err != nil {
+			NewtUsage(cmd, err)
+		}
+
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "%s\n",
+			builder.RevdepGraphTree(dg, []string{lpkgs[0].FullName()}))
+		return
+	}
+
 	if len(dg) > 0 {
-		util.StatusMessage(util.VERBOSITY_DEFAULT,
-			builder.RevdepGraphText(dg)+"\n")
+		printDepGraph(dg, true)
 	}
 }
 
+// Equivalent to `revdep --format=dot`.
 func targetRevdepvizCmd(cmd *cobra.Command, args []string) {
-	dg := targetRevdepCommonCmd(cmd, args)
+	depFormat = builder.DEP_GRAPH_FORMAT_DOT
+	targetRevdepCmd(cmd, args)
+}
 
-	if len(dg) > 0 {
-		fmt.Print(builder.RevdepGraphViz(dg))
+// Resolves the specified target and returns the names of all packages in its
+// master set.  The target package itself is excluded, as it always differs
+// between targets.
+func targetResolvedPkgNames(cmd *cobra.Command, name string) map[string]bool {
+	closure, err := TargetDependencyClosure(name)
+	if err != nil {
+		NewtUsage(nil, err)
+	}
+
+	names := make(map[string]bool, len(closure))
+	for _, rpkg := range closure {
+		if rpkg.Lpkg.Type() != pkg.PACKAGE_TYPE_TARGET {
+			names[rpkg.Lpkg.FullName()] = true
+		}
+	}
+
+	return names
+}
+
+func targetDepdiffCmd(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		NewtUsage(cmd, util.NewNewtError("Must specify two target names"))
+	}
+
+	TryGetProject()
+
+	namesA := targetResolvedPkgNames(cmd, args[0])
+	namesB := targetResolvedPkgNames(cmd, args[1])
+
+	// Packages in B but not A.
+	added := []string{}
+	for name, _ := range namesB {
+		if !namesA[name] {
+			added = append(added, name)
+		}
+	}
+	sort.Strings(added)
+
+	// Packages in A but not B.
+	removed := []string{}
+	for name, _ := range namesA {
+		if !namesB[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	// Show both lists unless the user asked for just one.
+	showAll := !depdiffAdded && !depdiffRemoved
+
+	if showAll || depdiffAdded {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "Added (%s --> %s):\n",
+			args[0], args[1])
+		for _, name := range added {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "    + %s\n", name)
+		}
+	}
+	if showAll || depdiffRemoved {
+		util.StatusMessage(util.VERBOSITY_DEFAULT, "Removed (%s --> %s):\n",
+			args[0], args[1])
+		for _, name := range removed {
+			util.StatusMessage(util.VERBOSITY_DEFAULT, "    - %s\n", name)
+		}
 	}
 }
 
 func AddTargetCommands(cmd *cobra.Command) {
-	targetHelpText := ""
+	targetHelpText := "Commands to create, delete, configure, and query " +
+		"targets.\n\n" +
+		"The app, bsp, loader, build_profile, header_size, and key_file " +
+		"variables can be overridden with environment variables of the " +
+		"form NEWT_TARGET_<TARGET>_<VARIABLE>, where <TARGET> is the full " +
+		"target name, uppercased, with non-alphanumeric characters " +
+		"replaced by '_' (e.g., " +
+		"NEWT_TARGET_TARGETS_MY_BLINKY_BUILD_PROFILE=optimized).  If " +
+		"several targets map to the same variable name, newt warns and " +
+		"the override applies to all of them.  An environment " +
+		"override takes precedence over target.yml; it affects builds but " +
+		"is never saved, and `newt target show` displays the saved values."
 	targetHelpEx := ""
 	targetCmd := &cobra.Command{
 		Use:     "target",
@@ -736,6 +3732,9 @@ func AddTargetCommands(cmd *cobra.Command) {
 		},
 	}
 
+	targetCmd.PersistentFlags().BoolVar(&noForeignWarn, "no-foreign-warn",
+		false, "Don't warn when modifying a target from an installed repo")
+
 	cmd.AddCommand(targetCmd)
 
 	showHelpText := "Show all the variables for the target specified " +
@@ -752,9 +3751,102 @@ func AddTargetCommands(cmd *cobra.Command) {
 	}
 	showCmd.Flags().BoolVarP(&showAll, "all", "a", false,
 		"Show all targets (including from other repos)")
+	showCmd.Flags().StringVar(&targetRepoName, "repo", "",
+		"Only show targets from the specified repo; also filters named "+
+			"targets")
+	showCmd.Flags().BoolVar(&showMissing, "missing", false,
+		"Report unset required variables ("+
+			strings.Join(requiredVars, ", ")+") instead of all variables")
+	showCmd.Flags().StringVar(&showBaseline, "baseline", "",
+		"Only show variables whose values differ from those of the "+
+			"specified target")
+	showCmd.Flags().BoolVar(&showWatch, "watch", false,
+		"Keep running, and show the targets again whenever one of their "+
+			"configuration files changes")
+	showCmd.Flags().DurationVar(&showInterval, "interval", time.Second,
+		"How often --watch checks for changes (e.g., 500ms, 2s)")
+	showCmd.Flags().BoolVar(&showResolveFlags, "resolve-flags", false,
+		"Resolve the target and list the build flags contributed by each "+
+			"package; flags from the target, app, and BSP apply globally")
+	showCmd.Flags().StringVar(&showOrder, "order", "alpha",
+		"Order of displayed variables: alpha, or logical (packages, "+
+			"other variables, build flags, syscfg)")
+	showCmd.Flags().BoolVar(&showOnlyOverrides, "only-overrides", false,
+		"Only show settings explicitly specified by the target, listing "+
+			"syscfg values individually")
+	showCmd.Flags().StringVar(&showGroup, "group", "",
+		"Show the targets listed in a group file (a YAML file with a "+
+			"targets list), followed by their common and divergent "+
+			"variables")
+	showCmd.Flags().BoolVar(&showHash, "hash", false,
+		"Print a hash of each target's configuration (variables, syscfg "+
+			"values, and build flags); targets with identical "+
+			"configurations have the same hash")
+	showCmd.Flags().IntVar(&showWidth, "width", 0,
+		"Wrap long values at the specified column (default: the terminal "+
+			"width if stdout is a terminal, otherwise no wrapping); 0 "+
+			"disables wrapping")
+	showCmd.Flags().StringVar(&showColor, "color", "auto",
+		"Color output: auto, always, or never (auto honors NO_COLOR and "+
+			"disables color when stdout is not a terminal)")
+	showCmd.Flags().BoolVar(&showDefaultsSource, "include-defaults-source",
+		false, "List syscfg values individually, marking each as "+
+			"(default) or (override)")
+	showCmd.Flags().BoolVar(&showEffective, "effective", false,
+		"List syscfg values individually, resolving references to other "+
+			"settings (e.g., ${MSYS_1_BLOCK_SIZE})")
+	showCmd.Flags().BoolVar(&showAppOnly, "app-only", false,
+		"Resolve the target and only show the syscfg settings and build "+
+			"flags defined or overridden by its app package")
+	showCmd.Flags().BoolVar(&showResolveRefs, "resolve-refs", false,
+		"Annotate package variables ("+strings.Join(pkgRefVars, ", ")+
+			") with the repo and path of the package they refer to")
 	targetCmd.AddCommand(showCmd)
 	AddTabCompleteFn(showCmd, targetList)
 
+	flagsHelpText := "Print the build flags (" + strings.Join(flagVars, ", ") +
+		") of the target specified by <target-name>."
+	flagsHelpEx := "  newt target flags my_target1\n"
+	flagsHelpEx += "  newt target flags --resolved --json my_target1"
+
+	flagsCmd := &cobra.Command{
+		Use:     "flags <target-name>",
+		Short:   "View target build flags",
+		Long:    flagsHelpText,
+		Example: flagsHelpEx,
+		Run:     targetFlagsCmd,
+	}
+	flagsCmd.Flags().BoolVar(&flagsJson, "json", false,
+		"Print flags as JSON arrays")
+	flagsCmd.Flags().BoolVar(&flagsResolved, "resolved", false,
+		"Include flags from the target's app and BSP packages")
+	targetCmd.AddCommand(flagsCmd)
+	AddTabCompleteFn(flagsCmd, targetList)
+
+	normalizePathsHelpText := "Rewrite the -I and -L paths in the build " +
+		"flags (" + strings.Join(flagVars, ", ") + ") of <target-name> " +
+		"in a canonical form: relative to the project directory, where " +
+		"builds run, with redundant \"./\" and \"..\" elements removed.  " +
+		"Absolute paths inside the project are made relative, so the " +
+		"target builds the same way in any checkout location.  Paths " +
+		"outside the project are reported and left unchanged.\n\n" +
+		"Paths are not made relative to the target package: the " +
+		"compiler resolves them against the project directory, so a " +
+		"package-relative path would refer to a different directory."
+	normalizePathsHelpEx := "  newt target normalize-paths my_target1"
+
+	normalizePathsCmd := &cobra.Command{
+		Use:     "normalize-paths <target-name>",
+		Short:   "Normalize include and library paths in target build flags",
+		Long:    normalizePathsHelpText,
+		Example: normalizePathsHelpEx,
+		Run:     targetNormalizePathsCmd,
+	}
+	normalizePathsCmd.Flags().BoolVarP(&newtutil.NewtForce, "force", "f",
+		false, "Modify the target even if it is locked")
+	targetCmd.AddCommand(normalizePathsCmd)
+	AddTabCompleteFn(normalizePathsCmd, targetList)
+
 	listHelpText := "List all available targets."
 	listHelpEx := "  newt target list"
 
@@ -767,6 +3859,8 @@ func AddTargetCommands(cmd *cobra.Command) {
 	}
 	listCmd.Flags().BoolVarP(&listAll, "all", "a", false,
 		"List all targets (including from other repos)")
+	listCmd.Flags().StringVar(&targetRepoName, "repo", "",
+		"Only list targets from the specified repo")
 	targetCmd.AddCommand(listCmd)
 
 	cmakeHelpText := "Generate CMakeLists.txt for target specified " +
@@ -792,11 +3886,23 @@ func AddTargetCommands(cmd *cobra.Command) {
 	setHelpText += "is created and the current settings are deleted. Only the settings\n"
 	setHelpText += "specified in the command are saved in the syscfg.yml file."
 	setHelpText += "\nIf you want to change or add a new syscfg value and keep the other\n"
-	setHelpText += "syscfg values, use the newt target amend command.\n"
+	setHelpText += "syscfg values, use the newt target amend command.\n\n"
+	setHelpText += "A syscfg setting specified without a value (e.g.,\n"
+	setHelpText += "syscfg=LOG_NEWTMGR) is set to 1, enabling a boolean setting.\n\n"
+	setHelpText += "Surrounding quotes and extra whitespace are removed from build\n"
+	setHelpText += "flag values.  Prefix a value with a backslash to use it verbatim.\n\n"
+	setHelpText += "Other target variables (e.g., ones read by a BSP) can be set\n"
+	setHelpText += "with --allow-unknown.\n\n"
+	setHelpText += "Setting a build flag variable replaces all of its flags, and\n"
+	setHelpText += "setting it to an empty value deletes it.  To remove individual\n"
+	setHelpText += "flags, use <var-name>-=<flags> (equivalent to amend -d).\n"
 	setHelpEx := "  newt target set my_target1 build_profile=optimized "
 	setHelpEx += "cflags=\"-DNDEBUG\"\n"
 	setHelpEx += "  newt target set my_target1 "
 	setHelpEx += "syscfg=LOG_NEWTMGR=1:CONFIG_NEWTMGR=0\n"
+	setHelpEx += "  newt target set --allow-unknown my_target1 jlink_serial=123\n"
+	setHelpEx += "  newt target set my_target1 cflags-=\"-DDEBUG\"\n"
+	setHelpEx += "  newt target set --batch < target_settings.txt\n"
 
 	setCmd := &cobra.Command{
 		Use: "set <target-name> <var-name>=<value> " +
@@ -806,13 +3912,34 @@ func AddTargetCommands(cmd *cobra.Command) {
 		Example: setHelpEx,
 		Run:     targetSetCmd,
 	}
+	setCmd.Flags().BoolVar(&setIfUnset, "if-unset", false,
+		"Only set variables that do not already have a value")
+	setCmd.Flags().BoolVar(&flagExpandHome, "expand-home", false,
+		"Expand ~ to the home directory in build flag values; the "+
+			"resulting paths are machine-specific")
+	setCmd.Flags().BoolVar(&setNoSave, "no-save", false,
+		"Display the resulting target without saving it")
+	setCmd.Flags().BoolVar(&setAllowUnknown, "allow-unknown", false,
+		"Allow setting custom target variables")
+	setCmd.Flags().BoolVar(&syscfgStrict, "strict", false,
+		"Fail if a syscfg value does not match its setting's type, "+
+			"choices, or range")
+	setCmd.Flags().BoolVarP(&newtutil.NewtForce, "force", "f", false,
+		"Modify the target even if it is locked")
+	setCmd.Flags().BoolVar(&targetBatch, "batch", false,
+		"Read set specifications from stdin, one per line")
+	setCmd.Flags().BoolVar(&targetAtomic, "atomic", false,
+		"With --batch, restore every target if any of them cannot be saved")
 	targetCmd.AddCommand(setCmd)
-	AddTabCompleteFn(setCmd, targetList)
+	AddArgCompleteFn(setCmd, targetSetComplete)
 
 	amendHelpText := "Add, change, or delete values for multi-value target variables\n\n"
 	amendHelpText += "Variables that can have values amended are:\n"
 	amendHelpText += strings.Join(amendVars, "\n") + "\n\n"
-	amendHelpText += "To change the value for a single value variable, such as bsp, use the\nnewt target set command.\n"
+	amendHelpText += "To change the value for a single value variable, such as bsp, use the\nnewt target set command.\n\n"
+	amendHelpText += "A syscfg setting specified without a value (e.g., syscfg=LOG_NEWTMGR)\n"
+	amendHelpText += "is set to 1.  With --delete, syscfg settings are deleted by name, so\n"
+	amendHelpText += "syscfg=LOG_NEWTMGR deletes the setting rather than enabling it.\n"
 
 	amendHelpEx := "  newt target amend my_target cflags=\"-DNDEBUG -DTEST\"\n"
 	amendHelpEx += "    Adds -DDEBUG and -DTEST to cflags\n\n"
@@ -821,7 +3948,12 @@ func AddTargetCommands(cmd *cobra.Command) {
 	amendHelpEx += "    Adds -Lmylib to lflags and syscfg variables LOG_LEVEL=1 and CONFIG_NEWTMGR=0\n\n"
 	amendHelpEx += "  newt target amend my_target -d syscfg=CONFIG_NEWTMGR "
 	amendHelpEx += "cflags=\"-DNDEBUG\"\n"
-	amendHelpEx += "    Deletes syscfg variable CONFIG_NEWTMGR and -DNDEBUG from cflags\n"
+	amendHelpEx += "    Deletes syscfg variable CONFIG_NEWTMGR and -DNDEBUG from cflags\n\n"
+	amendHelpEx += "  newt target amend my_target syscfg=OS_MAIN_STACK_SIZE+=256\n"
+	amendHelpEx += "    Increases the current value of OS_MAIN_STACK_SIZE by 256\n\n"
+	amendHelpEx += "  newt target amend 'prod_*' cflags=\"-DRELEASE\"\n"
+	amendHelpEx += "  newt target amend --targets=prod_a,prod_b cflags=\"-DRELEASE\"\n"
+	amendHelpEx += "    Adds -DRELEASE to cflags of each selected target\n"
 
 	amendCmd := &cobra.Command{
 		Use: "amend <target-name> <var-name>=<value>" +
@@ -833,12 +3965,79 @@ func AddTargetCommands(cmd *cobra.Command) {
 	}
 	amendCmd.Flags().BoolVarP(&amendDelete, "delete", "d", false,
 		"Delete Variable values")
+	amendCmd.Flags().BoolVar(&amendPrefix, "prefix", false,
+		"With --delete, remove every flag that starts with a given value")
+	amendCmd.Flags().BoolVar(&flagExpandHome, "expand-home", false,
+		"Expand ~ to the home directory in build flag values; the "+
+			"resulting paths are machine-specific")
+	amendCmd.Flags().BoolVar(&amendIfExists, "if-exists", false,
+		"Only change syscfg settings that the target already sets")
+	amendCmd.Flags().BoolVar(&amendWarnRedundant, "warn-redundant", false,
+		"Warn about syscfg values that equal the setting's default")
+	amendCmd.Flags().BoolVar(&syscfgStrict, "strict", false,
+		"Fail if a syscfg value does not match its setting's type, "+
+			"choices, or range")
+	amendCmd.Flags().BoolVarP(&newtutil.NewtForce, "force", "f", false,
+		"Modify the target even if it is locked")
+	amendCmd.Flags().BoolVar(&targetBatch, "batch", false,
+		"Read amend specifications from stdin, one per line")
+	amendCmd.Flags().BoolVar(&targetAtomic, "atomic", false,
+		"With --batch, --targets, or a target name pattern, modify no "+
+			"target unless every target can be amended and saved")
+	amendCmd.Flags().StringSliceVar(&amendTargets, "targets", nil,
+		"Amend each of the specified targets or target name patterns; "+
+			"all arguments are then variable=value pairs")
 	targetCmd.AddCommand(amendCmd)
 	AddTabCompleteFn(amendCmd, targetList)
 
+	importHelpText := "Merge syscfg settings from <settings-file> into " +
+		"target <target-name>.\n\n"
+	importHelpText += "Each line of the file contains one or more settings " +
+		"in the form\n<setting>=<value>, separated by colons.  Blank lines " +
+		"and lines starting\nwith '#' are ignored.  Existing settings not " +
+		"in the file are kept unless\n--replace is specified."
+	importHelpEx := "  newt target import-syscfg my_target overrides.txt\n"
+	importHelpEx += "  newt target import-syscfg --replace my_target overrides.txt"
+
+	importCmd := &cobra.Command{
+		Use:     "import-syscfg <target-name> <settings-file>",
+		Short:   "Merge syscfg settings from a file into a target",
+		Long:    importHelpText,
+		Example: importHelpEx,
+		Run:     targetImportSyscfgCmd,
+	}
+	importCmd.Flags().BoolVar(&importReplace, "replace", false,
+		"Replace the target's syscfg settings rather than merging")
+	importCmd.Flags().BoolVar(&syscfgStrict, "strict", false,
+		"Fail if a syscfg value does not match its setting's type, "+
+			"choices, or range")
+	targetCmd.AddCommand(importCmd)
+	AddTabCompleteFn(importCmd, targetList)
+
+	copySyscfgHelpText := "Copy the syscfg settings of <src-target> into " +
+		"<dst-target>.\n\nThe settings are merged on top of the " +
+		"destination's existing settings\nunless --replace is specified.  " +
+		"No other target variables are modified."
+	copySyscfgHelpEx := "  newt target copy-syscfg blinky_sim my_target\n"
+	copySyscfgHelpEx += "  newt target copy-syscfg --replace blinky_sim my_target"
+
+	copySyscfgCmd := &cobra.Command{
+		Use:     "copy-syscfg <src-target> <dst-target>",
+		Short:   "Copy syscfg settings from one target to another",
+		Long:    copySyscfgHelpText,
+		Example: copySyscfgHelpEx,
+		Run:     targetCopySyscfgCmd,
+	}
+	copySyscfgCmd.Flags().BoolVar(&copySyscfgReplace, "replace", false,
+		"Replace the destination's syscfg settings rather than merging")
+	targetCmd.AddCommand(copySyscfgCmd)
+	AddTabCompleteFn(copySyscfgCmd, targetList)
+
 	createHelpText := "Create a target specified by <target-name>."
 	createHelpEx := "  newt target create <target-name>\n"
-	createHelpEx += "  newt target create my_target1"
+	createHelpEx += "  newt target create my_target1\n"
+	createHelpEx += "  newt target create targets/prod/board_a\n"
+	createHelpEx += "  newt target create --copy-from blinky_sim my_target1"
 
 	createCmd := &cobra.Command{
 		Use:     "create",
@@ -847,12 +4046,19 @@ func AddTargetCommands(cmd *cobra.Command) {
 		Example: createHelpEx,
 		Run:     targetCreateCmd,
 	}
+	createCmd.Flags().StringVar(&createCopyFrom, "copy-from", "",
+		"Initialize the new target with a copy of an existing target")
+	createCmd.Flags().BoolVarP(&newtutil.NewtForce, "force", "f", false,
+		"Create the target even if it overwrites an existing package")
 
 	targetCmd.AddCommand(createCmd)
 
-	delHelpText := "Delete the target specified by <target-name>."
+	delHelpText := "Delete the target specified by <target-name>.  A " +
+		"target name may be a glob pattern, in which case every matching " +
+		"target is deleted."
 	delHelpEx := "  newt target delete <target-name>\n"
-	delHelpEx += "  newt target delete my_target1"
+	delHelpEx += "  newt target delete my_target1\n"
+	delHelpEx += "  newt target delete --safe 'tmp_*'"
 
 	delCmd := &cobra.Command{
 		Use:     "delete",
@@ -863,12 +4069,67 @@ func AddTargetCommands(cmd *cobra.Command) {
 	}
 	delCmd.PersistentFlags().BoolVarP(&newtutil.NewtForce,
 		"force", "f", false,
-		"Force delete of targets with user files without prompt")
+		"Force delete of targets with user files or locked targets "+
+			"without prompt")
+	delCmd.Flags().BoolVar(&delSafe, "safe", false,
+		"Skip targets that are referenced by other packages (e.g., mfg "+
+			"packages)")
 
 	targetCmd.AddCommand(delCmd)
 
-	copyHelpText := "Create a new target <dst-target> by cloning <src-target>"
-	copyHelpEx := "  newt target copy blinky_sim my_target"
+	applyHelpText := "Apply the settings in template <template> to target " +
+		"<target-name>.\n\n"
+	applyHelpText += "A template is a YAML file in the project's " +
+		TARGET_TEMPLATE_DIR + " directory\n(or a path to such a file).  " +
+		"It may contain the following sections:\n"
+	applyHelpText += "    template.set:      target variables to set\n"
+	applyHelpText += "    template.syscfg:   syscfg settings to amend\n"
+	applyHelpText += "    template.cflags (and aflags, cxxflags, lflags): " +
+		"build flags to add\n\n"
+	applyHelpText += "Templates can be composed with the $import directive."
+	applyHelpEx := "  newt target apply my_target production-lowpower\n"
+	applyHelpEx += "  newt target apply my_target path/to/template.yml"
+
+	applyCmd := &cobra.Command{
+		Use:     "apply <target-name> <template>",
+		Short:   "Apply a settings template to a target",
+		Long:    applyHelpText,
+		Example: applyHelpEx,
+		Run:     targetApplyCmd,
+	}
+	applyCmd.Flags().BoolVarP(&newtutil.NewtForce, "force", "f", false,
+		"Modify the target even if it is locked")
+
+	targetCmd.AddCommand(applyCmd)
+	AddTabCompleteFn(applyCmd, targetList)
+
+	lockHelpText := "Lock the targets specified by <target-name> so that " +
+		"they cannot be\nmodified or deleted without --force.  Use " +
+		"--unlock to remove the lock."
+	lockHelpEx := "  newt target lock my_release_target\n"
+	lockHelpEx += "  newt target lock --unlock my_release_target"
+
+	lockCmd := &cobra.Command{
+		Use:     "lock <target-name> [target-name...]",
+		Short:   "Protect targets from modification",
+		Long:    lockHelpText,
+		Example: lockHelpEx,
+		Run:     targetLockCmd,
+	}
+	lockCmd.Flags().BoolVar(&lockUndo, "unlock", false,
+		"Unlock the targets instead")
+
+	targetCmd.AddCommand(lockCmd)
+	AddTabCompleteFn(lockCmd, targetList)
+
+	copyHelpText := "Create a new target <dst-target> by cloning " +
+		"<src-target>.\n\nWith --merge-syscfg, <dst-target> may already " +
+		"exist.  In that case only\nthe syscfg settings of <src-target> " +
+		"are merged into it; settings\ndefined by both targets keep the " +
+		"destination's value unless\n--prefer-source is specified."
+	copyHelpEx := "  newt target copy blinky_sim my_target\n"
+	copyHelpEx += "  newt target copy blinky_sim targets/prod/board_a\n"
+	copyHelpEx += "  newt target copy --merge-syscfg blinky_sim my_target"
 
 	copyCmd := &cobra.Command{
 		Use:     "copy <src-target> <dst-target>",
@@ -877,10 +4138,35 @@ func AddTargetCommands(cmd *cobra.Command) {
 		Example: copyHelpEx,
 		Run:     targetCopyCmd,
 	}
+	copyCmd.Flags().BoolVar(&copyVerify, "verify", false,
+		"Ensure the new target resolves; remove it if it does not")
+	copyCmd.Flags().BoolVar(&copyMergeSyscfg, "merge-syscfg", false,
+		"Merge syscfg settings into an existing destination target")
+	copyCmd.Flags().BoolVar(&copyPreferSource, "prefer-source", false,
+		"When merging, let the source's syscfg values take precedence")
 
 	targetCmd.AddCommand(copyCmd)
 	AddTabCompleteFn(copyCmd, targetList)
 
+	renamePkgHelpText := "Rename a package in the local repo and update " +
+		"every local package that lists it in pkg.deps.  The package " +
+		"directory is moved and its pkg.name is rewritten.  Since this " +
+		"modifies several files, -f is required; without it, the packages " +
+		"that would be updated are listed."
+	renamePkgHelpEx := "  newt target rename-pkg -f libs/mylib libs/newlib"
+
+	renamePkgCmd := &cobra.Command{
+		Use:     "rename-pkg <old-pkg-name> <new-pkg-name>",
+		Short:   "Rename a package and update its dependents",
+		Long:    renamePkgHelpText,
+		Example: renamePkgHelpEx,
+		Run:     targetRenamePkgCmd,
+	}
+	renamePkgCmd.Flags().BoolVarP(&newtutil.NewtForce, "force", "f", false,
+		"Rename the package and rewrite its dependents")
+
+	targetCmd.AddCommand(renamePkgCmd)
+
 	depHelpText := "View a target's dependency graph."
 
 	depCmd := &cobra.Command{
@@ -889,21 +4175,64 @@ func AddTargetCommands(cmd *cobra.Command) {
 		Long:  depHelpText,
 		Run:   targetDepCmd,
 	}
-
+	depCmd.Flags().BoolVar(&depTree, "tree", false,
+		"Display the dependency graph as an indented tree")
+	depCmd.Flags().BoolVar(&depLongest, "longest", false,
+		"Display the longest dependency chain (by package count)")
+	depCmd.Flags().BoolVar(&depFlat, "flat", false,
+		"With --format=json, list each package's name, type, repo, and "+
+			"repo version instead of the graph's edges")
+	depCmd.Flags().BoolVar(&depTimings, "timings", false,
+		"Print the time spent resolving, building, and rendering the graph")
+	depCmd.Flags().BoolVar(&depReverse, "reverse", false,
+		"Display the reverse-dependency graph (same as the revdep command)")
+	depCmd.Flags().StringVar(&depRoot, "root", "",
+		"Only show the dependencies of the specified package")
+	depCmd.Flags().IntVar(&depDepth, "depth", 0,
+		"Maximum depth of dependencies to show with --root (0 = no limit)")
+	depCmd.Flags().StringSliceVar(&depExclude, "exclude", nil,
+		"Omit packages matching the specified glob pattern; may be repeated")
+	depCmd.Flags().StringVar(&depFormat, "format",
+		builder.DEP_GRAPH_FORMAT_TEXT,
+		"Output format: "+strings.Join(builder.DepGraphFormats, ", "))
+	depCmd.Flags().BoolVar(&depvizCombined, "combined", false,
+		"With --format=dot, include reverse dependencies; reverse edges "+
+			"are drawn dashed")
+	depCmd.Flags().StringVar(&depvizFocus, "focus", "",
+		"Only show the specified package, the packages it depends on, and "+
+			"the packages that depend on it")
+	depCmd.Flags().BoolVar(&depWarningsAsErrors, "warnings-as-errors", false,
+		"Exit with an error if any warnings are emitted while resolving "+
+			"the graph (e.g., a specified package is not in the target)")
 	targetCmd.AddCommand(depCmd)
 	AddTabCompleteFn(depCmd, func() []string {
 		return append(targetList(), unittestList()...)
 	})
 
-	depvizHelpText := "Output dependency graph in DOT format."
+	depvizHelpText := "Output dependency graph in DOT format.  This is " +
+		"equivalent to \"newt target dep --format=dot\"."
 
 	depvizCmd := &cobra.Command{
-		Use:   "depviz <target> [pkg-1] [pkg-2] [...]",
-		Short: "Output dependency graph in DOT format",
-		Long:  depvizHelpText,
-		Run:   targetDepvizCmd,
+		Use:        "depviz <target> [pkg-1] [pkg-2] [...]",
+		Short:      "Output dependency graph in DOT format",
+		Long:       depvizHelpText,
+		Run:        targetDepvizCmd,
+		Deprecated: "use \"newt target dep --format=dot\" instead",
 	}
-
+	depvizCmd.Flags().StringVar(&depRoot, "root", "",
+		"Only show the dependencies of the specified package")
+	depvizCmd.Flags().IntVar(&depDepth, "depth", 0,
+		"Maximum depth of dependencies to show with --root (0 = no limit)")
+	depvizCmd.Flags().BoolVar(&depvizCombined, "combined", false,
+		"Include reverse dependencies; reverse edges are drawn dashed")
+	depvizCmd.Flags().StringVar(&depvizFocus, "focus", "",
+		"Only show the specified package, the packages it depends on, and "+
+			"the packages that depend on it")
+	depvizCmd.Flags().StringSliceVar(&depExclude, "exclude", nil,
+		"Omit packages matching the specified glob pattern; may be repeated")
+	depvizCmd.Flags().BoolVar(&depWarningsAsErrors, "warnings-as-errors", false,
+		"Exit with an error if any warnings are emitted while resolving "+
+			"the graph (e.g., a specified package is not in the target)")
 	targetCmd.AddCommand(depvizCmd)
 	AddTabCompleteFn(depvizCmd, func() []string {
 		return append(targetList(), unittestList()...)
@@ -917,26 +4246,69 @@ func AddTargetCommands(cmd *cobra.Command) {
 		Long:  revdepHelpText,
 		Run:   targetRevdepCmd,
 	}
-
+	revdepCmd.Flags().BoolVar(&revdepDirect, "direct", false,
+		"Only list the packages that directly depend on each specified "+
+			"package; this is the default when packages are specified "+
+			"without --root")
+	revdepCmd.Flags().StringSliceVar(&depExclude, "exclude", nil,
+		"Omit packages matching the specified glob pattern; may be repeated")
+	revdepCmd.Flags().StringVar(&depRoot, "root", "",
+		"Display the packages that depend on the specified package, "+
+			"directly or indirectly, as a tree")
+	revdepCmd.Flags().IntVar(&depDepth, "depth", 0,
+		"Maximum depth of reverse dependencies to show with --root "+
+			"(0 = no limit)")
+	revdepCmd.Flags().StringVar(&depFormat, "format",
+		builder.DEP_GRAPH_FORMAT_TEXT,
+		"Output format: "+strings.Join(builder.DepGraphFormats, ", "))
+	revdepCmd.Flags().BoolVar(&depWarningsAsErrors, "warnings-as-errors", false,
+		"Exit with an error if any warnings are emitted while resolving "+
+			"the graph (e.g., a specified package is not in the target)")
 	targetCmd.AddCommand(revdepCmd)
 	AddTabCompleteFn(revdepCmd, func() []string {
 		return append(targetList(), unittestList()...)
 	})
 
-	revdepvizHelpText := "Output reverse-dependency graph in DOT format."
+	revdepvizHelpText := "Output reverse-dependency graph in DOT format.  " +
+		"This is equivalent to \"newt target revdep --format=dot\"."
 
 	revdepvizCmd := &cobra.Command{
-		Use:   "revdepviz <target> [pkg-1] [pkg-2] [...]",
-		Short: "Output reverse-dependency graph in DOT format",
-		Long:  revdepvizHelpText,
-		Run:   targetRevdepvizCmd,
+		Use:        "revdepviz <target> [pkg-1] [pkg-2] [...]",
+		Short:      "Output reverse-dependency graph in DOT format",
+		Long:       revdepvizHelpText,
+		Run:        targetRevdepvizCmd,
+		Deprecated: "use \"newt target revdep --format=dot\" instead",
 	}
-
+	revdepvizCmd.Flags().StringSliceVar(&depExclude, "exclude", nil,
+		"Omit packages matching the specified glob pattern; may be repeated")
+	revdepvizCmd.Flags().BoolVar(&depWarningsAsErrors, "warnings-as-errors", false,
+		"Exit with an error if any warnings are emitted while resolving "+
+			"the graph (e.g., a specified package is not in the target)")
 	targetCmd.AddCommand(revdepvizCmd)
 	AddTabCompleteFn(revdepvizCmd, func() []string {
 		return append(targetList(), unittestList()...)
 	})
 
+	depdiffHelpText := "Compare the resolved package sets of two targets.  " +
+		"Lists the packages added and removed going from <target-a> to " +
+		"<target-b>."
+
+	depdiffCmd := &cobra.Command{
+		Use:   "depdiff <target-a> <target-b>",
+		Short: "Compare the resolved packages of two targets",
+		Long:  depdiffHelpText,
+		Run:   targetDepdiffCmd,
+	}
+	depdiffCmd.Flags().BoolVar(&depdiffAdded, "added", false,
+		"Only list packages present in <target-b> but not <target-a>")
+	depdiffCmd.Flags().BoolVar(&depdiffRemoved, "removed", false,
+		"Only list packages present in <target-a> but not <target-b>")
+
+	targetCmd.AddCommand(depdiffCmd)
+	AddTabCompleteFn(depdiffCmd, func() []string {
+		return append(targetList(), unittestList()...)
+	})
+
 	for _, cmd := range targetCfgCmdAll() {
 		targetCmd.AddCommand(cmd)
 	}
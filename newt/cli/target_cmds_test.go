@@ -0,0 +1,401 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/config"
+	"mynewt.apache.org/newt/newt/target"
+	"mynewt.apache.org/newt/newt/testutil"
+)
+
+func TestRemoveBuildFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		cur     []string
+		del     []string
+		prefix  bool
+		want    []string
+		removed int
+	}{
+		{
+			name:    "exact",
+			cur:     []string{"-DFOO=1", "-DBAR", "-Wall"},
+			del:     []string{"-DBAR"},
+			want:    []string{"-DFOO=1", "-Wall"},
+			removed: 1,
+		},
+		{
+			// Without --prefix, a partial match is kept.
+			name:    "exact partial",
+			cur:     []string{"-DFOO=1", "-DFOO=2"},
+			del:     []string{"-DFOO"},
+			want:    []string{"-DFOO=1", "-DFOO=2"},
+			removed: 0,
+		},
+		{
+			name:    "prefix",
+			cur:     []string{"-DFOO=1", "-DFOO=2", "-DBAR"},
+			del:     []string{"-DFOO"},
+			prefix:  true,
+			want:    []string{"-DBAR"},
+			removed: 2,
+		},
+		{
+			name:    "multiple",
+			cur:     []string{"-DFOO", "-DBAR", "-DBAZ", "-Wall"},
+			del:     []string{"-DBAZ", "-DFOO"},
+			want:    []string{"-DBAR", "-Wall"},
+			removed: 2,
+		},
+		{
+			name:    "duplicates",
+			cur:     []string{"-DFOO", "-Wall", "-DFOO"},
+			del:     []string{"-DFOO"},
+			want:    []string{"-Wall"},
+			removed: 2,
+		},
+		{
+			name:    "no match",
+			cur:     []string{"-DFOO"},
+			del:     []string{"-DNOPE"},
+			prefix:  true,
+			want:    []string{"-DFOO"},
+			removed: 0,
+		},
+		{
+			name:    "remove all",
+			cur:     []string{"-DFOO"},
+			del:     []string{"-D"},
+			prefix:  true,
+			want:    []string{},
+			removed: 1,
+		},
+		{
+			name:    "empty",
+			cur:     nil,
+			del:     []string{"-DFOO"},
+			want:    []string{},
+			removed: 0,
+		},
+	}
+
+	for _, test := range tests {
+		got, removed := removeBuildFlags(test.cur, test.del, test.prefix)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v; want %v", test.name, got, test.want)
+		}
+		if removed != test.removed {
+			t.Errorf("%s: removed %d; want %d", test.name, removed,
+				test.removed)
+		}
+	}
+}
+
+// Verifies that copying a target copies its syscfg settings and the leading
+// comment block of its syscfg.yml file.
+func TestTargetCopyOneSyscfg(t *testing.T) {
+	_, cleanup := testutil.NewProject(t, map[string]string{
+		"targets/foo/syscfg.yml": "# Settings for foo.\n" +
+			"\n" +
+			"syscfg.vals:\n" +
+			"    A_VAL: 3  # Not the default.\n",
+	})
+	defer cleanup()
+
+	src := target.GetTargets()["targets/foo"]
+	dst, err := targetCopyOne(src, "targets/bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(dst.Package().SyscfgYamlPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Settings for foo.\n" +
+		"\n" +
+		"syscfg.vals:\n" +
+		"    A_VAL: 3\n"
+	if string(b) != want {
+		t.Errorf("copied syscfg.yml:\n%s\nwant:\n%s", string(b), want)
+	}
+
+	// The copy's settings are independent of the original's.
+	dst.Package().SyscfgY.Replace("syscfg.vals.A_VAL", "4")
+	vals, _ := src.Package().SyscfgY.GetValStringMapString("syscfg.vals", nil)
+	if vals["A_VAL"] != "3" {
+		t.Errorf("source A_VAL = %q after modifying copy; want 3",
+			vals["A_VAL"])
+	}
+}
+
+func TestNormalizeFlagPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   []string
+		want    []string
+		changed int
+	}{
+		{
+			name:    "unchanged",
+			flags:   []string{"-DFOO", "-Iinclude", "-Wall  -Werror"},
+			want:    []string{"-DFOO", "-Iinclude", "-Wall  -Werror"},
+			changed: 0,
+		},
+		{
+			name:    "attached",
+			flags:   []string{"-I./include", "-L/proj/lib/"},
+			want:    []string{"-Iinclude", "-Llib"},
+			changed: 2,
+		},
+		{
+			// A separated path in the same element stays in that element.
+			name:    "separated",
+			flags:   []string{"-I ./x", "-DFOO"},
+			want:    []string{"-I x", "-DFOO"},
+			changed: 1,
+		},
+		{
+			// A separated path in the next element stays in that element.
+			name:    "separate elements",
+			flags:   []string{"-I", "./x", "-DFOO"},
+			want:    []string{"-I", "x", "-DFOO"},
+			changed: 1,
+		},
+		{
+			// Only the changed element is rewritten.
+			name:    "mixed",
+			flags:   []string{"-DA  -DB", "-Ia/../b -Ic"},
+			want:    []string{"-DA  -DB", "-Ib -Ic"},
+			changed: 1,
+		},
+		{
+			name:    "outside project",
+			flags:   []string{"-I../other", "-I/usr/include"},
+			want:    []string{"-I../other", "-I/usr/include"},
+			changed: 0,
+		},
+	}
+
+	for _, test := range tests {
+		got, changed := normalizeFlagPaths("/proj", "cflags", test.flags)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q; want %q", test.name, got, test.want)
+		}
+		if changed != test.changed {
+			t.Errorf("%s: changed %d; want %d", test.name, changed,
+				test.changed)
+		}
+	}
+}
+
+// Verifies amending the syscfg settings of a target whose syscfg.yml file is
+// missing or empty.
+func TestAmendTargetSyscfgEmpty(t *testing.T) {
+	tests := []struct {
+		name   string
+		syscfg string
+		del    bool
+		vals   map[string]string
+		msgs   []string
+	}{
+		{
+			name:   "add missing",
+			syscfg: "",
+			vals:   map[string]string{"A_BOOL": "1"},
+			msgs: []string{
+				"Amended syscfg for Target targets/foo successfully",
+			},
+		},
+		{
+			name:   "add empty",
+			syscfg: "\n",
+			vals:   map[string]string{"A_BOOL": "1"},
+			msgs: []string{
+				"Amended syscfg for Target targets/foo successfully",
+			},
+		},
+		{
+			name:   "delete missing",
+			syscfg: "",
+			del:    true,
+			vals:   map[string]string{},
+			msgs:   []string{},
+		},
+		{
+			name:   "delete empty",
+			syscfg: "\n",
+			del:    true,
+			vals:   map[string]string{},
+			msgs:   []string{},
+		},
+	}
+
+	defer func() { amendDelete = false }()
+
+	for _, test := range tests {
+		_, cleanup := testutil.NewProject(t, map[string]string{
+			"targets/foo/syscfg.yml": test.syscfg,
+		})
+
+		amendDelete = test.del
+		tgt := target.GetTargets()["targets/foo"]
+		msgs, err := amendTarget(tgt, [][]string{{"syscfg", "A_BOOL=1"}})
+		if err != nil {
+			cleanup()
+			t.Fatalf("%s: unexpected error: %s", test.name, err.Error())
+		}
+
+		// A no-op delete must not create an empty syscfg.vals map.
+		hasVals := tgt.Package().SyscfgY.HasKey("syscfg.vals")
+		vals, err := tgt.Package().SyscfgY.GetValStringMapString(
+			"syscfg.vals", nil)
+		cleanup()
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err.Error())
+		}
+		if !reflect.DeepEqual(msgs, test.msgs) {
+			t.Errorf("%s: messages = %q; want %q", test.name, msgs, test.msgs)
+		}
+		if !reflect.DeepEqual(vals, test.vals) {
+			t.Errorf("%s: syscfg.vals = %v; want %v", test.name, vals,
+				test.vals)
+		}
+		if hasVals != (len(test.vals) > 0) {
+			t.Errorf("%s: syscfg.vals present = %t; want %t", test.name,
+				hasVals, len(test.vals) > 0)
+		}
+	}
+}
+
+// Verifies that `target set --if-unset` skips syscfg settings individually.
+func TestTargetSetIfUnsetSyscfg(t *testing.T) {
+	_, cleanup := testutil.NewProject(t, nil)
+	defer cleanup()
+
+	setIfUnset = true
+	defer func() { setIfUnset = false }()
+
+	tgt, msgs := targetSetApply(nil, []string{
+		"foo", "syscfg=A_VAL=1:A_BOOL=1", "build_profile=optimized",
+	})
+	if tgt == nil {
+		t.Fatal("target unexpectedly unchanged")
+	}
+
+	wantMsgs := []string{
+		"Target targets/foo successfully set target.syscfg to A_BOOL=1",
+	}
+	if !reflect.DeepEqual(msgs, wantMsgs) {
+		t.Errorf("messages = %q; want %q", msgs, wantMsgs)
+	}
+
+	vals, err := tgt.Package().SyscfgY.GetValStringMapString(
+		"syscfg.vals", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantVals := map[string]string{"A_VAL": "3", "A_BOOL": "1"}
+	if !reflect.DeepEqual(vals, wantVals) {
+		t.Errorf("syscfg.vals = %v; want %v", vals, wantVals)
+	}
+	if tgt.BuildProfile != "debug" {
+		t.Errorf("build_profile = %s; want debug", tgt.BuildProfile)
+	}
+}
+
+// Verifies that a failed save restores every target saved before it.
+func TestSaveTargetsAtomic(t *testing.T) {
+	_, cleanup := testutil.NewProject(t, map[string]string{
+		"targets/bar/pkg.yml": "pkg.name: targets/bar\npkg.type: target\n",
+		"targets/bar/target.yml": "target.app: apps/blinky\n" +
+			"target.bsp: hw/bsp/mybsp\n",
+	})
+	defer cleanup()
+
+	foo := target.GetTargets()["targets/foo"]
+	bar := target.GetTargets()["targets/bar"]
+
+	paths := []string{
+		foo.TargetYamlPath(),
+		foo.Package().SyscfgYamlPath(),
+		bar.TargetYamlPath(),
+	}
+	orig := map[string]string{}
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		orig[path] = string(b)
+	}
+
+	foo.TargetY.Replace("target.build_profile", "optimized")
+	foo.Package().SyscfgY.Replace("syscfg.vals", map[interface{}]interface{}{
+		"A_VAL": "4",
+	})
+	bar.TargetY.Replace("target.build_profile", "optimized")
+
+	// Force bar's last write (syscfg.yml) to fail after foo has been saved.
+	if err := os.Symlink("nonexistent/syscfg.yml",
+		bar.Package().SyscfgYamlPath()); err != nil {
+
+		t.Fatal(err)
+	}
+
+	err := saveTargetsAtomic([]*target.Target{foo, bar})
+	if err == nil {
+		t.Fatalf("saveTargetsAtomic succeeded; want error")
+	}
+
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		if string(b) != orig[path] {
+			t.Errorf("%s not restored:\n%s\nwant:\n%s", path, string(b),
+				orig[path])
+		}
+	}
+
+	// Without the failure, both targets are saved.
+	os.Remove(bar.Package().SyscfgYamlPath())
+	if err := saveTargetsAtomic([]*target.Target{foo, bar}); err != nil {
+		t.Fatal(err)
+	}
+	for _, tgt := range []*target.Target{foo, bar} {
+		yc, err := config.ReadFile(tgt.TargetYamlPath())
+		if err != nil {
+			t.Fatal(err)
+		}
+		profile, _ := yc.GetValString("target.build_profile", nil)
+		if profile != "optimized" {
+			t.Errorf("%s: saved build_profile = %q; want optimized",
+				tgt.FullName(), profile)
+		}
+	}
+}
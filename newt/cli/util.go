@@ -43,6 +43,7 @@ import (
 const TARGET_KEYWORD_ALL string = "all"
 const TARGET_DEFAULT_DIR string = "targets"
 const MFG_DEFAULT_DIR string = "mfgs"
+const TARGET_TEMPLATE_DIR string = "target_templates"
 
 func NewtUsage(cmd *cobra.Command, err error) {
 	if err != nil {
@@ -88,6 +89,9 @@ func ResolveTarget(name string) *target.Target {
 	// completion is used to specify the name.
 	name = strings.TrimSuffix(name, "/")
 
+	// Replace a user-defined alias with the name it refers to.
+	name = project.GetProject().ExpandAlias(name)
+
 	targetMap := target.GetTargets()
 
 	// Check for fully-qualified name.
@@ -142,6 +146,28 @@ func ResolveTargets(names ...string) ([]*target.Target, error) {
 	return targets, nil
 }
 
+// Resolves a list of target names.  Unlike ResolveTargets, an unknown name does
+// not cause the whole operation to fail; the names that could not be resolved
+// are returned alongside the targets that could.  The "all" keyword is not
+// accepted and is reported as unresolved.
+//
+// @return                      targets, unresolved-names
+func ResolveTargetsPartial(names ...string) ([]*target.Target, []string) {
+	targets := []*target.Target{}
+	var unresolved []string
+
+	for _, name := range names {
+		t := ResolveTarget(name)
+		if t == nil || name == TARGET_KEYWORD_ALL {
+			unresolved = append(unresolved, name)
+		} else {
+			targets = append(targets, t)
+		}
+	}
+
+	return targets, unresolved
+}
+
 func ResolveNewTargetName(name string) (string, error) {
 	repoName, pkgName, err := newtutil.ParsePackageString(name)
 	if err != nil {
@@ -163,10 +189,31 @@ func ResolveNewTargetName(name string) (string, error) {
 		pkgName = TARGET_DEFAULT_DIR + "/" + pkgName
 	}
 
+	// Nested names (e.g., "targets/prod/boardA") are allowed, but each path
+	// segment must be a plain directory name.
+	for _, seg := range strings.Split(pkgName, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return "", util.NewNewtError("Invalid target name: " + name)
+		}
+	}
+
 	if target.GetTargets()[pkgName] != nil {
 		return "", util.NewNewtError("Target already exists: " + pkgName)
 	}
 
+	// Don't allow a target directory to contain another target; deleting the
+	// outer target would also delete the inner one.
+	for tname, _ := range target.GetTargets() {
+		if strings.HasPrefix(pkgName, tname+"/") {
+			return "", util.FmtNewtError(
+				"Target %s cannot be nested inside target %s", pkgName, tname)
+		}
+		if strings.HasPrefix(tname, pkgName+"/") {
+			return "", util.FmtNewtError(
+				"Target %s would contain existing target %s", pkgName, tname)
+		}
+	}
+
 	return pkgName, nil
 }
 
@@ -296,6 +343,7 @@ func ResolvePackages(pkgNames []string) ([]*pkg.LocalPackage, error) {
 
 	lpkgs := []*pkg.LocalPackage{}
 	for _, pkgName := range pkgNames {
+		pkgName = proj.ExpandAlias(pkgName)
 		pack, err := proj.ResolvePackage(proj.LocalRepo(), pkgName)
 		if err != nil {
 			return nil, err
@@ -306,7 +354,7 @@ func ResolvePackages(pkgNames []string) ([]*pkg.LocalPackage, error) {
 	return lpkgs, nil
 }
 
-func ResolveRpkgs(res *resolve.Resolution, pkgNames []string) (
+func ResolveRpkgs(closure []*resolve.ResolvePackage, pkgNames []string) (
 	[]*resolve.ResolvePackage, error) {
 
 	lpkgs, err := ResolvePackages(pkgNames)
@@ -314,9 +362,15 @@ func ResolveRpkgs(res *resolve.Resolution, pkgNames []string) (
 		return nil, err
 	}
 
+	lpkgRpkgMap := make(map[*pkg.LocalPackage]*resolve.ResolvePackage,
+		len(closure))
+	for _, rpkg := range closure {
+		lpkgRpkgMap[rpkg.Lpkg] = rpkg
+	}
+
 	rpkgs := []*resolve.ResolvePackage{}
 	for _, lpkg := range lpkgs {
-		rpkg := res.LpkgRpkgMap[lpkg]
+		rpkg := lpkgRpkgMap[lpkg]
 		if rpkg == nil {
 			return nil, util.FmtNewtError("Unexpected error; local package "+
 				"%s lacks a corresponding resolve package", lpkg.FullName())
@@ -343,6 +397,40 @@ func TargetBuilderForTargetOrUnittest(pkgName string) (
 	}
 }
 
+// TargetDependencyClosure resolves the specified target or unittest and
+// returns every package it pulls in, sorted by name.
+func TargetDependencyClosure(pkgName string) (
+	[]*resolve.ResolvePackage, error) {
+
+	b, err := TargetBuilderForTargetOrUnittest(pkgName)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.DependencyClosure()
+}
+
+// Determines the default answer for prompts that confirm destructive
+// operations.  The --prompt-default option takes precedence over the project's
+// `project.prompt_default` setting.  Out of the box, the default is no.
+func DestructivePromptDefault() bool {
+	if newtutil.NewtPromptDefault != "" {
+		dflt, _ := newtutil.ParsePromptDefault(newtutil.NewtPromptDefault)
+		return dflt
+	}
+
+	return project.GetProject().PromptDefault()
+}
+
+// Returns the answer hint to display after a yes-or-no question, e.g.,
+// "(y/N)" if the default answer is no.
+func PromptYesNoHint(dflt bool) string {
+	if dflt {
+		return "(Y/n)"
+	}
+	return "(y/N)"
+}
+
 func PromptYesNo(dflt bool) bool {
 	scanner := bufio.NewScanner(os.Stdin)
 	rc := scanner.Scan()
@@ -0,0 +1,88 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/testutil"
+)
+
+func TestResolveTargetsPartial(t *testing.T) {
+	_, cleanup := testutil.NewProject(t, map[string]string{
+		"targets/bar/pkg.yml": "pkg.name: targets/bar\npkg.type: target\n",
+		"targets/bar/target.yml": "target.app: apps/blinky\n" +
+			"target.bsp: hw/bsp/mybsp\n",
+	})
+	defer cleanup()
+
+	tests := []struct {
+		names      []string
+		targets    []string
+		unresolved []string
+	}{
+		{
+			names:   []string{"foo", "bar"},
+			targets: []string{"targets/foo", "targets/bar"},
+		},
+		{
+			names:   []string{"targets/foo"},
+			targets: []string{"targets/foo"},
+		},
+		{
+			names:      []string{"foo", "nope", "bar"},
+			targets:    []string{"targets/foo", "targets/bar"},
+			unresolved: []string{"nope"},
+		},
+		{
+			names:      []string{"nope1", "nope2"},
+			targets:    []string{},
+			unresolved: []string{"nope1", "nope2"},
+		},
+		{
+			names:      []string{"all"},
+			targets:    []string{},
+			unresolved: []string{"all"},
+		},
+		{
+			names:   nil,
+			targets: []string{},
+		},
+	}
+
+	for _, test := range tests {
+		targets, unresolved := ResolveTargetsPartial(test.names...)
+
+		names := []string{}
+		for _, t := range targets {
+			names = append(names, t.FullName())
+		}
+
+		if !reflect.DeepEqual(names, test.targets) {
+			t.Errorf("ResolveTargetsPartial(%v): targets = %v; want %v",
+				test.names, names, test.targets)
+		}
+		if !reflect.DeepEqual(unresolved, test.unresolved) {
+			t.Errorf("ResolveTargetsPartial(%v): unresolved = %v; want %v",
+				test.names, unresolved, test.unresolved)
+		}
+	}
+}
@@ -21,6 +21,9 @@ package extcmd
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+
 	"mynewt.apache.org/newt/newt/cfgv"
 
 	"mynewt.apache.org/newt/newt/pkg"
@@ -39,6 +42,11 @@ type ExtCmdCfg struct {
 
 	// Strings describing errors encountered while parsing the extcmd config.
 	InvalidSettings []string
+
+	// Contains sets of commands assigned the same stage number.  Commands
+	// that share a stage are run in command-string order.
+	//     [stage-num] => <slice-of-commands-with-stage>
+	Conflicts map[int][]stage.StageFunc
 }
 
 // GetMapFn retrieves a set of ordered external commands from a package.  For
@@ -64,13 +72,31 @@ func (ecfg *ExtCmdCfg) readOnePkg(lpkg *pkg.LocalPackage, cfg *syscfg.Cfg,
 	}
 }
 
+// Searches the external command configuration for commands with identical
+// stage numbers.  The configuration object is populated with the results.
+func (ecfg *ExtCmdCfg) detectConflicts() {
+	m := map[int][]stage.StageFunc{}
+
+	for _, sf := range ecfg.StageFuncs {
+		stageNum, _ := sf.Stage.IntVal()
+		m[stageNum] = append(m[stageNum], sf)
+	}
+
+	for stageNum, sfs := range m {
+		if len(sfs) > 1 {
+			ecfg.Conflicts[stageNum] = sfs
+		}
+	}
+}
+
 // Read constructs an external command configuration from a full set of
 // packages.
 func Read(name string, lpkgs []*pkg.LocalPackage, cfg *syscfg.Cfg,
 	getMapCb GetMapFn) ExtCmdCfg {
 
 	ecfg := ExtCmdCfg{
-		Name: name,
+		Name:      name,
+		Conflicts: map[int][]stage.StageFunc{},
 	}
 
 	for _, lpkg := range lpkgs {
@@ -78,6 +104,7 @@ func Read(name string, lpkgs []*pkg.LocalPackage, cfg *syscfg.Cfg,
 	}
 
 	stage.SortStageFuncs(ecfg.StageFuncs, ecfg.Name)
+	ecfg.detectConflicts()
 
 	return ecfg
 }
@@ -97,3 +124,30 @@ func (ecfg *ExtCmdCfg) ErrorText() string {
 
 	return str
 }
+
+// ConflictWarning returns a warning line for each stage number that is
+// assigned to more than one command.  Such commands are still run in a
+// deterministic order, so a conflict does not prevent the build.
+func (ecfg *ExtCmdCfg) ConflictWarning() []string {
+	stageNums := make([]int, 0, len(ecfg.Conflicts))
+	for stageNum, _ := range ecfg.Conflicts {
+		stageNums = append(stageNums, stageNum)
+	}
+	sort.Ints(stageNums)
+
+	lines := []string{}
+	for _, stageNum := range stageNums {
+		cmds := []string{}
+		for _, sf := range ecfg.Conflicts[stageNum] {
+			cmds = append(cmds, fmt.Sprintf("%s (%s)",
+				sf.Name, sf.Pkg.FullName()))
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"%s commands share stage %d; assign unique stage numbers to "+
+				"control their order: %s",
+			ecfg.Name, stageNum, strings.Join(cmds, ", ")))
+	}
+
+	return lines
+}
@@ -23,6 +23,7 @@ import (
 	"mynewt.apache.org/newt/newt/settings"
 	"os"
 	"runtime"
+	"strconv"
 
 	"github.com/shirou/gopsutil/cpu"
 	log "github.com/sirupsen/logrus"
@@ -38,6 +39,7 @@ var newtQuiet bool
 var newtVerbose bool
 var newtLogFile string
 var newtNumJobs int
+var newtMaxProcs int
 var newtHelp bool
 
 func newtDfltNumJobs() int {
@@ -57,6 +59,27 @@ func newtDfltNumJobs() int {
 	return numJobs
 }
 
+// Determines the number of workers to use for dependency resolution.  The
+// --max-procs flag takes precedence, followed by the NEWT_MAX_PROCS
+// environment variable.  If neither is specified, the number of build jobs
+// (-j) is used.
+func newtResolveJobs() (int, error) {
+	if newtMaxProcs > 0 {
+		return newtMaxProcs, nil
+	}
+
+	if env := os.Getenv("NEWT_MAX_PROCS"); env != "" {
+		n, err := strconv.Atoi(env)
+		if err != nil || n <= 0 {
+			return 0, util.FmtNewtError(
+				"Invalid NEWT_MAX_PROCS value: \"%s\"", env)
+		}
+		return n, nil
+	}
+
+	return newtNumJobs, nil
+}
+
 func newtCmd() *cobra.Command {
 	newtHelpText := cli.FormatHelp(`Newt allows you to create your own embedded 
 		application based on the Mynewt operating system.  Newt provides both 
@@ -101,6 +124,19 @@ func newtCmd() *cobra.Command {
 			}
 
 			newtutil.NewtNumJobs = newtNumJobs
+
+			if newtutil.NewtPromptDefault != "" {
+				_, err := newtutil.ParsePromptDefault(
+					newtutil.NewtPromptDefault)
+				if err != nil {
+					cli.NewtUsage(nil, err)
+				}
+			}
+
+			newtutil.NewtResolveJobs, err = newtResolveJobs()
+			if err != nil {
+				cli.NewtUsage(nil, err)
+			}
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.Help()
@@ -119,6 +155,13 @@ func newtCmd() *cobra.Command {
 		"", "Filename to tee output to")
 	newtCmd.PersistentFlags().IntVarP(&newtNumJobs, "jobs", "j",
 		newtDfltNumJobs(), "Number of concurrent build jobs")
+	newtCmd.PersistentFlags().IntVar(&newtMaxProcs, "max-procs", 0,
+		"Number of concurrent dependency resolution workers "+
+			"(default: $NEWT_MAX_PROCS or the -j value)")
+	newtCmd.PersistentFlags().StringVar(&newtutil.NewtPromptDefault,
+		"prompt-default", "",
+		"Default answer (yes or no) for prompts confirming destructive "+
+			"operations (default: project.prompt_default or no)")
 	newtCmd.PersistentFlags().BoolVarP(&newtHelp, "help", "h",
 		false, "Help for newt commands")
 	newtCmd.PersistentFlags().BoolVarP(&util.EscapeShellCmds, "escape", "",
@@ -37,9 +37,17 @@ var NewtDate = "unknown"
 
 var NewtBlinkyTag string = "master"
 var NewtNumJobs int
+
+// Number of workers used during dependency resolution.  Package scanning
+// (reading pkg.yml files) is always performed serially.
+var NewtResolveJobs int
 var NewtForce bool
 var NewtAsk bool
 
+// Default answer for prompts that confirm destructive operations, as specified
+// with --prompt-default ("yes" or "no").  Empty if unspecified.
+var NewtPromptDefault string
+
 const CORE_REPO_NAME string = "apache-mynewt-core"
 const ARDUINO_ZERO_REPO_NAME string = "mynewt_arduino_zero"
 
@@ -162,6 +170,19 @@ func BuildPackageString(repoName string, pkgName string) string {
 	}
 }
 
+// Parses a default prompt answer: "yes" or "no" (or "y" / "n"), in any case.
+func ParsePromptDefault(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "y", "yes":
+		return true, nil
+	case "n", "no":
+		return false, nil
+	default:
+		return false, util.FmtNewtError(
+			"Invalid prompt default \"%s\"; must be \"yes\" or \"no\"", s)
+	}
+}
+
 func GeneratedPreamble() string {
 	return fmt.Sprintf(
 		"/**\n * This file was generated by Apache newt version: %s\n */\n\n",
@@ -0,0 +1,196 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// This file contains functions that edit the `pkg.deps` list of an existing
+// pkg.yml file.  Unlike Save(), these functions modify the file in place, so
+// comments, conditional sections, and fields newt doesn't save are preserved.
+
+package pkg
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	"mynewt.apache.org/newt/newt/config"
+	"mynewt.apache.org/newt/util"
+)
+
+var depItemRe = regexp.MustCompile(`^([ \t]+)-[ \t]*(["']?)(.*?)(["']?)[ \t]*$`)
+
+// Locates the unconditional `pkg.deps` block in the lines of a pkg.yml file.
+// Returns the index of the "pkg.deps:" line and the index just past the
+// block's last list item, or -1 if the file has no such block.
+func findDepsBlock(lines []string) (int, int, error) {
+	start := -1
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, " \t\r")
+		if strings.HasPrefix(trimmed, "pkg.deps:") {
+			if trimmed != "pkg.deps:" {
+				return -1, -1, util.NewNewtError(
+					"pkg.deps is not a block sequence; edit it manually")
+			}
+			start = i
+			break
+		}
+	}
+	if start == -1 {
+		return -1, -1, nil
+	}
+
+	end := start + 1
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if lines[i][0] != ' ' && lines[i][0] != '\t' {
+			break
+		}
+		end = i + 1
+	}
+
+	return start, end, nil
+}
+
+// Reads the package's pkg.yml file and locates its `pkg.deps` block.  An error
+// is returned if the package has unconditional dependencies that are not in a
+// top-level "pkg.deps:" block (e.g., a nested `pkg:` map).
+func (pkg *LocalPackage) readDepsBlock() ([]string, int, int, error) {
+	data, err := ioutil.ReadFile(pkg.PkgYamlPath())
+	if err != nil {
+		return nil, -1, -1, util.ChildNewtError(err)
+	}
+
+	lines := strings.Split(string(data), "\n")
+	start, end, err := findDepsBlock(lines)
+	if err != nil {
+		return nil, -1, -1, util.PreNewtError(err, "%s", pkg.PkgYamlPath())
+	}
+
+	if start == -1 {
+		deps, _ := pkg.PkgY.GetValStringSlice("pkg.deps", nil)
+		if len(deps) > 0 {
+			return nil, -1, -1, util.FmtNewtError(
+				"%s: pkg.deps is not a top-level key; edit it manually",
+				pkg.PkgYamlPath())
+		}
+	}
+
+	return lines, start, end, nil
+}
+
+// Writes the specified lines to the package's pkg.yml file and reloads the
+// package's pkg.yml contents.
+func (pkg *LocalPackage) writePkgYamlLines(lines []string) error {
+	s := strings.Join(lines, "\n")
+	if err := ioutil.WriteFile(pkg.PkgYamlPath(), []byte(s), 0644); err != nil {
+		return util.ChildNewtError(err)
+	}
+
+	yc, err := config.ReadFile(pkg.PkgYamlPath())
+	if err != nil {
+		return err
+	}
+	pkg.PkgY = yc
+
+	return nil
+}
+
+// AddDepEntry appends an entry to the package's unconditional `pkg.deps` list
+// and writes the pkg.yml file.  The list is created if it does not exist.  The
+// caller is responsible for ensuring the entry is not a duplicate.
+func (pkg *LocalPackage) AddDepEntry(depName string) error {
+	lines, start, end, err := pkg.readDepsBlock()
+	if err != nil {
+		return err
+	}
+
+	if start == -1 {
+		// Append a new block, keeping a single trailing newline.
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		lines = append(lines, "pkg.deps:", "    - "+depName, "")
+		return pkg.writePkgYamlLines(lines)
+	}
+
+	// Match the indentation of the existing entries.
+	indent := "    "
+	for _, line := range lines[start+1 : end] {
+		if m := depItemRe.FindStringSubmatch(line); m != nil {
+			indent = m[1]
+			break
+		}
+	}
+
+	newLines := make([]string, 0, len(lines)+1)
+	newLines = append(newLines, lines[:end]...)
+	newLines = append(newLines, indent+"- "+depName)
+	newLines = append(newLines, lines[end:]...)
+
+	return pkg.writePkgYamlLines(newLines)
+}
+
+// RemoveDepEntries removes every entry in the package's unconditional
+// `pkg.deps` list that is equal to one of the specified names, and writes the
+// pkg.yml file.  Optional entries match without their "?" suffix.  Returns
+// the number of entries removed.
+func (pkg *LocalPackage) RemoveDepEntries(depNames []string) (int, error) {
+	lines, start, end, err := pkg.readDepsBlock()
+	if err != nil {
+		return 0, err
+	}
+	if start == -1 {
+		return 0, nil
+	}
+
+	newLines := make([]string, 0, len(lines))
+	newLines = append(newLines, lines[:start+1]...)
+
+	removed := 0
+	for _, line := range lines[start+1 : end] {
+		if m := depItemRe.FindStringSubmatch(line); m != nil {
+			match := false
+			entry := strings.TrimSuffix(m[3], DEP_OPTIONAL_SUFFIX)
+			for _, name := range depNames {
+				if entry == name {
+					match = true
+					break
+				}
+			}
+			if match {
+				removed++
+				continue
+			}
+		}
+		newLines = append(newLines, line)
+	}
+	newLines = append(newLines, lines[end:]...)
+
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if err := pkg.writePkgYamlLines(newLines); err != nil {
+		return 0, err
+	}
+
+	return removed, nil
+}
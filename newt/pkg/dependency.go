@@ -20,14 +20,25 @@
 package pkg
 
 import (
+	"strings"
+
 	"mynewt.apache.org/newt/newt/interfaces"
 	"mynewt.apache.org/newt/newt/newtutil"
 	"mynewt.apache.org/newt/newt/repo"
 )
 
+// A `pkg.deps` entry ending in this suffix is an optional dependency: it is
+// only included in a build if another package requires the dependee (e.g.,
+// "@apache-mynewt-core/sys/stats/full?").
+const DEP_OPTIONAL_SUFFIX = "?"
+
 type Dependency struct {
 	Name string
 	Repo string
+
+	// Optional dependencies don't cause the dependee to be included in a
+	// build.
+	Optional bool
 }
 
 func (dep *Dependency) String() string {
@@ -71,6 +82,11 @@ func (dep *Dependency) setRepoAndName(parentRepo interfaces.RepoInterface, str s
 }
 
 func (dep *Dependency) Init(parentRepo interfaces.RepoInterface, depStr string) error {
+	if strings.HasSuffix(depStr, DEP_OPTIONAL_SUFFIX) {
+		dep.Optional = true
+		depStr = strings.TrimSuffix(depStr, DEP_OPTIONAL_SUFFIX)
+	}
+
 	if err := dep.setRepoAndName(parentRepo, depStr); err != nil {
 		return err
 	}
@@ -22,10 +22,12 @@ package pkg
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"mynewt.apache.org/newt/newt/cfgv"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"mynewt.apache.org/newt/newt/config"
 	"mynewt.apache.org/newt/newt/interfaces"
@@ -69,6 +71,10 @@ type LocalPackage struct {
 	// Settings read from syscfg.yml.
 	SyscfgY ycfg.YCfg
 
+	// Comment block to write at the top of a new syscfg.yml file.  Set when
+	// the package is cloned so that the copy keeps the original's header.
+	syscfgHeader string
+
 	// Names of all source yml files; used to determine if rebuild required.
 	cfgFilenames []string
 }
@@ -213,18 +219,64 @@ func (pkg *LocalPackage) sequenceString(key string) string {
 	}
 }
 
+// Extracts the block of comments at the top of the specified YAML file (e.g.,
+// a license header).  Blank lines within the block are retained.  Returns ""
+// if the file does not exist or does not start with a comment.
+func leadingCommentBlock(path string) string {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(string(data), "\n")
+	end := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			end = i + 1
+		} else if trimmed != "" {
+			break
+		}
+	}
+
+	if end == 0 {
+		return ""
+	}
+
+	return strings.Join(lines[:end], "\n") + "\n"
+}
+
+// Saves the package's syscfg.yml file.  A comment block at the top of the
+// existing file is preserved.
 func (lpkg *LocalPackage) SaveSyscfg() error {
-	dirpath := lpkg.BasePath()
+	return lpkg.SaveSyscfgTo(lpkg.BasePath())
+}
+
+// Writes the package's syscfg.yml file to the specified directory.  The
+// package's base path is not changed.  A comment block at the top of an
+// existing file in the destination is preserved.  If there is none, the
+// comment block of the package this one was cloned from is written.
+func (lpkg *LocalPackage) SaveSyscfgTo(dirpath string) error {
 	if err := os.MkdirAll(dirpath, 0755); err != nil {
 		return util.NewNewtError(err.Error())
 	}
 
-	file, err := os.Create(lpkg.SyscfgYamlPath())
+	path := fmt.Sprintf("%s/%s", dirpath, SYSCFG_YAML_FILENAME)
+	header := leadingCommentBlock(path)
+	if header == "" {
+		header = lpkg.syscfgHeader
+	}
+
+	file, err := os.Create(path)
 	if err != nil {
 		return util.NewNewtError(err.Error())
 	}
 	defer file.Close()
 
+	if header != "" {
+		file.WriteString(header + "\n")
+	}
+
 	s := lpkg.SyscfgY.YAML()
 	file.WriteString(s)
 
@@ -235,12 +287,18 @@ func (lpkg *LocalPackage) SaveSyscfg() error {
 // NOTE: This does not save every field in the package.  Only the fields
 // necessary for creating a new target get saved.
 func (pkg *LocalPackage) Save() error {
-	dirpath := pkg.BasePath()
+	return pkg.SaveTo(pkg.BasePath())
+}
+
+// Writes the package's pkg.yml file to the specified directory.  The
+// package's base path is not changed.  As with Save(), only a subset of the
+// package's fields are written.
+func (pkg *LocalPackage) SaveTo(dirpath string) error {
 	if err := os.MkdirAll(dirpath, 0755); err != nil {
 		return util.NewNewtError(err.Error())
 	}
 
-	file, err := os.Create(pkg.PkgYamlPath())
+	file, err := os.Create(fmt.Sprintf("%s/%s", dirpath, PACKAGE_FILE_NAME))
 	if err != nil {
 		return util.NewNewtError(err.Error())
 	}
@@ -250,7 +308,7 @@ func (pkg *LocalPackage) Save() error {
 	// cached settings individually.
 	file.WriteString("pkg.name: " + yaml.EscapeString(pkg.Name()) + "\n")
 	file.WriteString("pkg.type: " +
-		yaml.EscapeString(PackageTypeNames[pkg.Type()]) + "\n")
+		yaml.EscapeString(PackageTypeToString(pkg.Type())) + "\n")
 	file.WriteString("pkg.description: " +
 		yaml.EscapeString(pkg.Desc().Description) + "\n")
 	file.WriteString("pkg.author: " +
@@ -258,6 +316,12 @@ func (pkg *LocalPackage) Save() error {
 	file.WriteString("pkg.homepage: " +
 		yaml.EscapeString(pkg.Desc().Homepage) + "\n")
 
+	locked, err := pkg.PkgY.GetValBoolDflt("pkg.locked", nil, false)
+	util.OneTimeWarningError(err)
+	if locked {
+		file.WriteString("pkg.locked: true\n")
+	}
+
 	file.WriteString("\n")
 
 	file.WriteString(pkg.sequenceString("pkg.deps"))
@@ -272,7 +336,7 @@ func (pkg *LocalPackage) Save() error {
 func matchNamePath(name, path string) bool {
 	// assure that name and path use the same path separator...
 	names := strings.Split(name, "/")
-	name = strings.Join(names, "/")
+	name = filepath.Join(names...)
 
 	if strings.HasSuffix(path, name) {
 		return true
@@ -290,70 +354,26 @@ func (pkg *LocalPackage) Load() error {
 	}
 	pkg.AddCfgFilename(pkg.PkgYamlPath())
 
-	// Set package name from the package
-	pkg.name, err = pkg.PkgY.GetValString("pkg.name", nil)
-	util.OneTimeWarningError(err)
-	if pkg.name == "" {
-		return util.FmtNewtError(
-			"Package \"%s\" missing \"pkg.name\" field in its `pkg.yml` file",
-			pkg.basePath)
-	}
-
-	if !matchNamePath(pkg.name, pkg.basePath) {
-		return util.FmtNewtError(
-			"Package \"%s\" has incorrect \"pkg.name\" field in its "+
-				"`pkg.yml` file (pkg.name=%s)", pkg.basePath, pkg.name)
+	// Check the package for problems.  Warnings are displayed; an error
+	// prevents the package from being loaded.
+	if err := reportProblems(pkg.Validate()); err != nil {
+		return err
 	}
 
-	typeString, err := pkg.PkgY.GetValString("pkg.type", nil)
-	util.OneTimeWarningError(err)
-	pkg.packageType = PACKAGE_TYPE_LIB
-	if len(typeString) > 0 {
-		found := false
-		for t, n := range PackageTypeNames {
-			if typeString == n {
-				pkg.packageType = t
-				found = true
-				break
-			}
-		}
+	// Set package name from the package
+	pkg.name, _ = pkg.PkgY.GetValString("pkg.name", nil)
 
-		if !found {
-			return util.FmtNewtError(
-				"Package \"%s\" has incorrect \"pkg.type\" field in its "+
-					"`pkg.yml` file (pkg.type=%s)", pkg.basePath, typeString)
-		}
-	}
+	typeString, _ := pkg.PkgY.GetValString("pkg.type", nil)
+	pkg.packageType, _ = parsePackageType(typeString)
 
 	if pkg.packageType == PACKAGE_TYPE_TRANSIENT {
-		n, err := pkg.PkgY.GetValString("pkg.link", nil)
-		util.OneTimeWarningError(err)
-		if len(n) == 0 {
-			return util.FmtNewtError(
-				"Transient package \"%s\" does not specify target "+
-					"package in its `pkg.yml` file (pkg.name=%s)",
-				pkg.basePath, pkg.name)
-		}
-
-		pkg.linkedName = n
+		pkg.linkedName, _ = pkg.PkgY.GetValString("pkg.link", nil)
 
 		// We don't really want anything else for this package
 		return nil
 	}
 
-	subPriority, err := pkg.PkgY.GetValInt("pkg.subpriority", nil)
-	util.OneTimeWarningError(err)
-	if subPriority >= PACKAGE_SUBPRIO_NUM {
-		return util.FmtNewtError(
-			"Package \"%s\" subpriority value \"%d\" is out of range (0 - \"%d\")",
-			pkg.basePath, subPriority, PACKAGE_SUBPRIO_NUM-1)
-	}
-	if subPriority > 0 && pkg.packageType >= PACKAGE_TYPE_BSP {
-		return util.FmtNewtError(
-			"Package \"%s\" of type \"%s\" does not support subpriorities",
-			pkg.basePath, typeString)
-	}
-	pkg.subPriority = subPriority
+	pkg.subPriority, _ = pkg.PkgY.GetValInt("pkg.subpriority", nil)
 
 	// Read the package description from the file
 	pkg.desc, err = pkg.readDesc(pkg.PkgY)
@@ -423,11 +443,15 @@ func (pkg *LocalPackage) Clone(newRepo *repo.Repo,
 
 	// XXX: Validate name.
 
-	// Copy the package.
+	// Copy the package.  The YAML contents are copied as well so that the
+	// clone can be modified independently of the original.
 	newPkg := *pkg
 	newPkg.repo = newRepo
 	newPkg.name = newName
 	newPkg.basePath = newRepo.Path() + "/" + newPkg.name
+	newPkg.PkgY = pkg.PkgY.Clone()
+	newPkg.SyscfgY = pkg.SyscfgY.Clone()
+	newPkg.syscfgHeader = leadingCommentBlock(pkg.SyscfgYamlPath())
 
 	// Insert the clone into the global package map.
 	proj := interfaces.GetProject()
@@ -455,15 +479,105 @@ func LocalPackageSpecialName(dirName string) bool {
 	return ok
 }
 
+// Minimum interval between progress reports during a package scan.
+const scanProgressInterval = time.Second
+
+// Tracks the progress of a package scan so that it can be reported to the
+// user.  Progress is only reported at verbose verbosity.
+type scanProgress struct {
+	repoName   string
+	dirCount   int
+	pkgCount   int
+	lastReport time.Time
+}
+
+func (sp *scanProgress) report(force bool) {
+	if sp == nil || util.Verbosity < util.VERBOSITY_VERBOSE {
+		return
+	}
+
+	now := time.Now()
+	if !force && now.Sub(sp.lastReport) < scanProgressInterval {
+		return
+	}
+	sp.lastReport = now
+
+	util.StatusMessage(util.VERBOSITY_VERBOSE,
+		"Scanning repo %s: %d directories searched, %d packages loaded\n",
+		sp.repoName, sp.dirCount, sp.pkgCount)
+}
+
+// Categories of problems encountered while scanning a repo for packages.
+const (
+	SCAN_WARNING_SEARCH_ERROR   = "search-error"
+	SCAN_WARNING_LOAD_ERROR     = "load-error"
+	SCAN_WARNING_DUPLICATE_NAME = "duplicate-name"
+	SCAN_WARNING_ORPHAN_SYSCFG  = "orphan-syscfg"
+)
+
+// ScanWarning describes a problem encountered while scanning a repo for
+// packages.  `Path` is the directory the problem was found in, relative to the
+// repo.
+type ScanWarning struct {
+	Category string `json:"category"`
+	Repo     string `json:"repo"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+func (w ScanWarning) String() string {
+	return w.Message
+}
+
+// ScanWarningStrings converts a set of scan warnings to the messages
+// displayed when a project is loaded.  Orphan syscfg files don't affect the
+// build, so they are omitted; they are only reported on request.
+func ScanWarningStrings(warnings []ScanWarning) []string {
+	strs := []string{}
+	for _, w := range warnings {
+		if w.Category != SCAN_WARNING_ORPHAN_SYSCFG {
+			strs = append(strs, w.String())
+		}
+	}
+
+	return strs
+}
+
 func ReadLocalPackageRecursive(repo *repo.Repo,
 	pkgList map[string]interfaces.PackageInterface, basePath string,
 	pkgName string, searchedMap map[string]struct{}) ([]string, error) {
 
-	var warnings []string
+	warnings, err := readLocalPackageRecursive(repo, pkgList, basePath,
+		pkgName, searchedMap, nil)
+
+	return ScanWarningStrings(warnings), err
+}
+
+func readLocalPackageRecursive(repo *repo.Repo,
+	pkgList map[string]interfaces.PackageInterface, basePath string,
+	pkgName string, searchedMap map[string]struct{},
+	progress *scanProgress) ([]ScanWarning, error) {
+
+	var warnings []ScanWarning
+
+	warn := func(category string, msg string) {
+		warnings = append(warnings, ScanWarning{
+			Category: category,
+			Repo:     repo.Name(),
+			Path:     pkgName,
+			Message:  msg,
+		})
+	}
 
 	dirList, err := repo.FilteredSearchList(pkgName, searchedMap)
 	if err != nil {
-		return append(warnings, err.Error()), nil
+		warn(SCAN_WARNING_SEARCH_ERROR, err.Error())
+		return warnings, nil
+	}
+
+	if progress != nil {
+		progress.dirCount++
+		progress.report(false)
 	}
 
 	for _, name := range dirList {
@@ -471,8 +585,8 @@ func ReadLocalPackageRecursive(repo *repo.Repo,
 			continue
 		}
 
-		subWarnings, err := ReadLocalPackageRecursive(repo, pkgList,
-			basePath, filepath.Join(pkgName, name), searchedMap)
+		subWarnings, err := readLocalPackageRecursive(repo, pkgList,
+			basePath, filepath.Join(pkgName, name), searchedMap, progress)
 		warnings = append(warnings, subWarnings...)
 		if err != nil {
 			return warnings, err
@@ -480,18 +594,26 @@ func ReadLocalPackageRecursive(repo *repo.Repo,
 	}
 
 	if util.NodeNotExist(filepath.Join(basePath, pkgName, PACKAGE_FILE_NAME)) {
+		// A syscfg.yml file outside of a package is never read.
+		if pkgName != "" && util.NodeExist(
+			filepath.Join(basePath, pkgName, SYSCFG_YAML_FILENAME)) {
+
+			warn(SCAN_WARNING_ORPHAN_SYSCFG, fmt.Sprintf(
+				"%s in directory without %s: %s", SYSCFG_YAML_FILENAME,
+				PACKAGE_FILE_NAME, filepath.Join(basePath, pkgName)))
+		}
 		return warnings, nil
 	}
 
 	pkg, err := LoadLocalPackage(repo, filepath.Join(basePath, pkgName))
 	if err != nil {
-		warnings = append(warnings, err.Error())
+		warn(SCAN_WARNING_LOAD_ERROR, err.Error())
 		return warnings, nil
 	}
 
 	if oldPkg, ok := pkgList[pkg.Name()]; ok {
 		oldlPkg := oldPkg.(*LocalPackage)
-		warnings = append(warnings,
+		warn(SCAN_WARNING_DUPLICATE_NAME,
 			fmt.Sprintf("Multiple packages with same pkg.name=%s "+
 				"in repo %s; path1=%s path2=%s", oldlPkg.Name(), repo.Name(),
 				oldlPkg.BasePath(), pkg.BasePath()))
@@ -500,6 +622,9 @@ func ReadLocalPackageRecursive(repo *repo.Repo,
 	}
 
 	pkgList[pkg.Name()] = pkg
+	if progress != nil {
+		progress.pkgCount++
+	}
 
 	return warnings, nil
 }
@@ -507,15 +632,31 @@ func ReadLocalPackageRecursive(repo *repo.Repo,
 func ReadLocalPackages(repo *repo.Repo, basePath string) (
 	*map[string]interfaces.PackageInterface, []string, error) {
 
+	pkgMap, warnings, err := ScanLocalPackages(repo, basePath)
+	return pkgMap, ScanWarningStrings(warnings), err
+}
+
+// ScanLocalPackages is like ReadLocalPackages, but returns the problems it
+// encounters as structured warnings.
+func ScanLocalPackages(repo *repo.Repo, basePath string) (
+	*map[string]interfaces.PackageInterface, []ScanWarning, error) {
+
 	pkgMap := &map[string]interfaces.PackageInterface{}
 
 	// Keep track of which directories we have traversed.  Prevent infinite
 	// loops caused by symlink cycles by not inspecting the same directory
-	// twice.
+	// twice.  Directories are keyed by their real (symlink-free) path.
 	searchedMap := map[string]struct{}{}
 
-	warnings, err := ReadLocalPackageRecursive(repo, *pkgMap,
-		basePath, "", searchedMap)
+	progress := &scanProgress{
+		repoName:   repo.Name(),
+		lastReport: time.Now(),
+	}
+
+	warnings, err := readLocalPackageRecursive(repo, *pkgMap,
+		basePath, "", searchedMap, progress)
+
+	progress.report(true)
 
 	return pkgMap, warnings, err
 }
@@ -19,7 +19,14 @@
 
 package pkg
 
-import "mynewt.apache.org/newt/newt/interfaces"
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"mynewt.apache.org/newt/newt/interfaces"
+	"mynewt.apache.org/newt/util"
+)
 
 const PACKAGE_FILE_NAME = "pkg.yml"
 const SYSCFG_YAML_FILENAME = "syscfg.yml"
@@ -61,6 +68,35 @@ var PackageTypeNames = map[interfaces.PackageType]string{
 	PACKAGE_TYPE_TARGET:    "target",
 }
 
+// PackageTypeFromString converts a package type name (e.g., "lib") to a
+// package type.  The returned error lists the valid type names.
+func PackageTypeFromString(s string) (interfaces.PackageType, error) {
+	for t, n := range PackageTypeNames {
+		if s == n {
+			return t, nil
+		}
+	}
+
+	names := make([]string, 0, len(PackageTypeNames))
+	for _, n := range PackageTypeNames {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return PACKAGE_TYPE_LIB, util.FmtNewtError(
+		"Invalid package type: %s; must be one of: %s",
+		s, strings.Join(names, ", "))
+}
+
+// PackageTypeToString converts a package type to its name.
+func PackageTypeToString(t interfaces.PackageType) string {
+	if n, ok := PackageTypeNames[t]; ok {
+		return n
+	}
+
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
 // An interface, representing information about a Package
 // This interface is implemented by both packages in the
 // local directory, but also packages that are stored in
@@ -0,0 +1,57 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package pkg
+
+import (
+	"testing"
+
+	"mynewt.apache.org/newt/newt/interfaces"
+)
+
+func TestPackageTypeRoundTrip(t *testing.T) {
+	for typ, name := range PackageTypeNames {
+		if s := PackageTypeToString(typ); s != name {
+			t.Errorf("PackageTypeToString(%d) = %s; want %s", typ, s, name)
+		}
+
+		got, err := PackageTypeFromString(name)
+		if err != nil {
+			t.Errorf("PackageTypeFromString(%s): unexpected error: %s",
+				name, err.Error())
+			continue
+		}
+		if got != typ {
+			t.Errorf("PackageTypeFromString(%s) = %d; want %d", name, got, typ)
+		}
+	}
+}
+
+func TestPackageTypeInvalid(t *testing.T) {
+	for _, s := range []string{"", "bogus", "LIB", " lib"} {
+		if _, err := PackageTypeFromString(s); err == nil {
+			t.Errorf("PackageTypeFromString(%q): expected error", s)
+		}
+	}
+
+	unknown := interfaces.PackageType(len(PackageTypeNames) + 10)
+	if s := PackageTypeToString(unknown); s != "unknown(21)" {
+		t.Errorf("PackageTypeToString(%d) = %s; want unknown(21)", unknown, s)
+	}
+}
@@ -0,0 +1,260 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"mynewt.apache.org/newt/newt/config"
+	"mynewt.apache.org/newt/newt/interfaces"
+	"mynewt.apache.org/newt/newt/repo"
+	"mynewt.apache.org/newt/util"
+)
+
+const (
+	PROBLEM_SEVERITY_WARNING = "warning"
+	PROBLEM_SEVERITY_ERROR   = "error"
+)
+
+// PackageProblem describes an issue with a package's `pkg.yml` file.  A
+// problem with "error" severity prevents the package from being loaded.
+type PackageProblem struct {
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (p PackageProblem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.File, p.Message)
+}
+
+// Converts a `pkg.type` string to a package type.  Returns false if the string
+// does not name a valid type.  An empty string indicates a library.
+func parsePackageType(typeString string) (interfaces.PackageType, bool) {
+	if typeString == "" {
+		return PACKAGE_TYPE_LIB, true
+	}
+
+	t, err := PackageTypeFromString(typeString)
+	return t, err == nil
+}
+
+// Validate checks the package's `pkg.yml` contents and returns the problems it
+// finds.  Nothing is printed.  The package's `pkg.yml` file must already have
+// been read.
+func (pkg *LocalPackage) Validate() []PackageProblem {
+	problems := []PackageProblem{}
+
+	add := func(severity string, field string, msg string) {
+		problems = append(problems, PackageProblem{
+			Severity: severity,
+			File:     pkg.PkgYamlPath(),
+			Field:    field,
+			Message:  msg,
+		})
+	}
+	addErr := func(field string, err error) {
+		if err != nil {
+			add(PROBLEM_SEVERITY_WARNING, field, err.Error())
+		}
+	}
+
+	name, err := pkg.PkgY.GetValString("pkg.name", nil)
+	addErr("pkg.name", err)
+	if name == "" {
+		add(PROBLEM_SEVERITY_ERROR, "pkg.name", fmt.Sprintf(
+			"Package \"%s\" missing \"pkg.name\" field in its `pkg.yml` file",
+			pkg.basePath))
+
+		// Without a name, the remaining checks are of little use.
+		return problems
+	}
+
+	if !matchNamePath(name, pkg.basePath) {
+		add(PROBLEM_SEVERITY_ERROR, "pkg.name", fmt.Sprintf(
+			"Package \"%s\" has incorrect \"pkg.name\" field in its "+
+				"`pkg.yml` file (pkg.name=%s)", pkg.basePath, name))
+	}
+
+	typeString, err := pkg.PkgY.GetValString("pkg.type", nil)
+	addErr("pkg.type", err)
+	packageType, ok := parsePackageType(typeString)
+	if !ok {
+		add(PROBLEM_SEVERITY_ERROR, "pkg.type", fmt.Sprintf(
+			"Package \"%s\" has incorrect \"pkg.type\" field in its "+
+				"`pkg.yml` file (pkg.type=%s)", pkg.basePath, typeString))
+	}
+
+	if packageType == PACKAGE_TYPE_TRANSIENT {
+		link, err := pkg.PkgY.GetValString("pkg.link", nil)
+		addErr("pkg.link", err)
+		if link == "" {
+			add(PROBLEM_SEVERITY_ERROR, "pkg.link", fmt.Sprintf(
+				"Transient package \"%s\" does not specify target "+
+					"package in its `pkg.yml` file (pkg.name=%s)",
+				pkg.basePath, name))
+		}
+
+		// No other fields are used by transient packages.
+		return problems
+	}
+
+	subPriority, err := pkg.PkgY.GetValInt("pkg.subpriority", nil)
+	addErr("pkg.subpriority", err)
+	if subPriority >= PACKAGE_SUBPRIO_NUM {
+		add(PROBLEM_SEVERITY_ERROR, "pkg.subpriority", fmt.Sprintf(
+			"Package \"%s\" subpriority value \"%d\" is out of range "+
+				"(0 - \"%d\")",
+			pkg.basePath, subPriority, PACKAGE_SUBPRIO_NUM-1))
+	}
+	if subPriority > 0 && packageType >= PACKAGE_TYPE_BSP {
+		add(PROBLEM_SEVERITY_ERROR, "pkg.subpriority", fmt.Sprintf(
+			"Package \"%s\" of type \"%s\" does not support subpriorities",
+			pkg.basePath, typeString))
+	}
+
+	for _, field := range []string{
+		"pkg.description", "pkg.author", "pkg.homepage",
+	} {
+		_, err := pkg.PkgY.GetValString(field, nil)
+		addErr(field, err)
+	}
+
+	_, err = pkg.PkgY.GetValStringSlice("pkg.keywords", nil)
+	addErr("pkg.keywords", err)
+
+	return problems
+}
+
+// Extracts the directories specified by `-I` options in a list of compiler
+// flags.  Both "-I<dir>" and "-I <dir>" forms are recognized.
+func includeDirs(flags []string) []string {
+	tokens := []string{}
+	for _, f := range flags {
+		tokens = append(tokens, strings.Fields(f)...)
+	}
+
+	dirs := []string{}
+	for i := 0; i < len(tokens); i++ {
+		if !strings.HasPrefix(tokens[i], "-I") {
+			continue
+		}
+
+		dir := strings.TrimPrefix(tokens[i], "-I")
+		if dir == "" {
+			if i+1 >= len(tokens) {
+				continue
+			}
+			i++
+			dir = tokens[i]
+		}
+		dirs = append(dirs, dir)
+	}
+
+	return dirs
+}
+
+// ValidateIncludes checks that the include directories specified by `-I`
+// options in the package's `pkg.cflags` and `pkg.cxxflags` exist.  Relative
+// directories are resolved against the project root, since that is the
+// directory the compiler runs in.  A missing
+// directory is reported as a warning; it does not prevent the package from
+// being loaded, but usually causes a build failure.
+func (pkg *LocalPackage) ValidateIncludes() []PackageProblem {
+	problems := []PackageProblem{}
+	projPath := interfaces.GetProject().Path()
+
+	for _, field := range []string{"pkg.cflags", "pkg.cxxflags"} {
+		flags, err := pkg.PkgY.GetValStringSlice(field, nil)
+		if err != nil {
+			problems = append(problems, PackageProblem{
+				Severity: PROBLEM_SEVERITY_WARNING,
+				File:     pkg.PkgYamlPath(),
+				Field:    field,
+				Message:  err.Error(),
+			})
+			continue
+		}
+
+		for _, dir := range includeDirs(flags) {
+			full := dir
+			if !filepath.IsAbs(full) {
+				full = filepath.Join(projPath, dir)
+			}
+
+			if util.NodeNotExist(full) {
+				problems = append(problems, PackageProblem{
+					Severity: PROBLEM_SEVERITY_WARNING,
+					File:     pkg.PkgYamlPath(),
+					Field:    field,
+					Message: fmt.Sprintf(
+						"Package \"%s\" include directory does not exist: "+
+							"-I%s (%s)", pkg.basePath, dir, full),
+				})
+			}
+		}
+	}
+
+	return problems
+}
+
+// Displays the warnings in the specified set of problems and returns the first
+// error, if any.
+func reportProblems(problems []PackageProblem) error {
+	for _, p := range problems {
+		if p.Severity == PROBLEM_SEVERITY_WARNING {
+			util.OneTimeWarning("%s", p.Message)
+		}
+	}
+
+	for _, p := range problems {
+		if p.Severity == PROBLEM_SEVERITY_ERROR {
+			return util.NewNewtError(p.Message)
+		}
+	}
+
+	return nil
+}
+
+// ValidateDir reads the `pkg.yml` file in the specified directory and returns
+// its problems.  Unlike loading the package, this succeeds even if the package
+// is invalid.  An error is returned only if the file cannot be read.  If
+// `includes` is true, the package's include directories are also checked.
+func ValidateDir(r *repo.Repo, pkgDir string,
+	includes bool) ([]PackageProblem, error) {
+
+	lpkg := NewLocalPackage(r, pkgDir)
+
+	yc, err := config.ReadFile(lpkg.PkgYamlPath())
+	if err != nil {
+		return nil, err
+	}
+	lpkg.PkgY = yc
+
+	problems := lpkg.Validate()
+	if includes {
+		problems = append(problems, lpkg.ValidateIncludes()...)
+	}
+
+	return problems, nil
+}
@@ -0,0 +1,173 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/interfaces"
+)
+
+// Implements just enough of a project for include validation.
+type testProject struct {
+	interfaces.ProjectInterface
+	path string
+}
+
+func (proj testProject) Path() string {
+	return proj.path
+}
+
+func TestValidateDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		pkgYml   string
+		includes bool
+		// Directories to create, relative to the package directory.
+		dirs []string
+		// Expected severity of each problem, in order.
+		severities []string
+	}{
+		{
+			name:       "libs/ok",
+			pkgYml:     "pkg.name: libs/ok\npkg.type: lib\n",
+			severities: nil,
+		},
+		{
+			name:       "libs/noname",
+			pkgYml:     "pkg.type: lib\n",
+			severities: []string{PROBLEM_SEVERITY_ERROR},
+		},
+		{
+			name:       "libs/wrongname",
+			pkgYml:     "pkg.name: libs/other\npkg.type: lib\n",
+			severities: []string{PROBLEM_SEVERITY_ERROR},
+		},
+		{
+			name:       "libs/badtype",
+			pkgYml:     "pkg.name: libs/badtype\npkg.type: bogus\n",
+			severities: []string{PROBLEM_SEVERITY_ERROR},
+		},
+		{
+			name: "libs/badprio",
+			pkgYml: "pkg.name: libs/badprio\npkg.type: lib\n" +
+				"pkg.subpriority: 100\n",
+			severities: []string{PROBLEM_SEVERITY_ERROR},
+		},
+		{
+			name: "libs/transient",
+			pkgYml: "pkg.name: libs/transient\n" +
+				"pkg.type: transient\n",
+			severities: []string{PROBLEM_SEVERITY_ERROR},
+		},
+		{
+			name: "libs/badinc",
+			pkgYml: "pkg.name: libs/badinc\npkg.type: lib\n" +
+				"pkg.cflags: -Inope\n",
+			includes:   true,
+			severities: []string{PROBLEM_SEVERITY_WARNING},
+		},
+		{
+			name: "libs/badinc_unchecked",
+			pkgYml: "pkg.name: libs/badinc_unchecked\npkg.type: lib\n" +
+				"pkg.cflags: -Inope\n",
+			includes:   false,
+			severities: nil,
+		},
+		{
+			// Relative include directories are resolved against the
+			// project root.
+			name: "libs/projinc",
+			pkgYml: "pkg.name: libs/projinc\npkg.type: lib\n" +
+				"pkg.cflags: -Iinclude\n",
+			includes:   true,
+			severities: nil,
+		},
+		{
+			name: "libs/pkginc",
+			pkgYml: "pkg.name: libs/pkginc\npkg.type: lib\n" +
+				"pkg.cflags: -Iinc\n",
+			includes:   true,
+			dirs:       []string{"inc"},
+			severities: []string{PROBLEM_SEVERITY_WARNING},
+		},
+		{
+			name: "libs/splitinc",
+			pkgYml: "pkg.name: libs/splitinc\npkg.type: lib\n" +
+				"pkg.cflags: [-I, libs/splitinc/inc]\n",
+			includes:   true,
+			dirs:       []string{"inc"},
+			severities: nil,
+		},
+	}
+
+	base, err := ioutil.TempDir("", "newt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+
+	if err := os.Mkdir(filepath.Join(base, "include"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldProj := interfaces.GetProject()
+	interfaces.SetProject(testProject{path: base})
+	defer interfaces.SetProject(oldProj)
+
+	for _, test := range tests {
+		dir := filepath.Join(base, filepath.FromSlash(test.name))
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range test.dirs {
+			err := os.MkdirAll(filepath.Join(dir, d), 0755)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err := ioutil.WriteFile(filepath.Join(dir, PACKAGE_FILE_NAME),
+			[]byte(test.pkgYml), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		problems, err := ValidateDir(nil, dir, test.includes)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err.Error())
+			continue
+		}
+
+		if len(problems) != len(test.severities) {
+			t.Errorf("%s: got %d problems (%v); want %d", test.name,
+				len(problems), problems, len(test.severities))
+			continue
+		}
+		for i, p := range problems {
+			if p.Severity != test.severities[i] {
+				t.Errorf("%s: problem %d has severity %s; want %s",
+					test.name, i, p.Severity, test.severities[i])
+			}
+		}
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -78,10 +79,25 @@ type Project struct {
 
 	warnings []string
 
+	// Problems encountered while scanning repos for packages.
+	scanWarnings []pkg.ScanWarning
+
 	// Indicates the repos whose version we couldn't detect.  Prevents
 	// duplicate warnings.
 	unknownRepoVers map[string]struct{}
 
+	// User-defined package name aliases, as read from `project.yml`.
+	// [alias-name] => <package-name>
+	aliases map[string]string
+
+	// Whether targets store their syscfg settings in target.yml rather than
+	// syscfg.yml (`project.target_inline_syscfg`).
+	targetInlineSyscfg bool
+
+	// Default answer for prompts confirming destructive operations
+	// (`project.prompt_default`).
+	promptDefault bool
+
 	yc ycfg.YCfg
 }
 
@@ -210,6 +226,19 @@ func (proj *Project) Name() string {
 	return proj.name
 }
 
+// PromptDefault returns the default answer for prompts that confirm
+// destructive operations, as specified by `project.prompt_default`.  The
+// default is no (false).
+func (proj *Project) PromptDefault() bool {
+	return proj.promptDefault
+}
+
+// TargetInlineSyscfg indicates whether targets save their syscfg settings in
+// their target.yml file instead of their base package's syscfg.yml file.
+func (proj *Project) TargetInlineSyscfg() bool {
+	return proj.targetInlineSyscfg
+}
+
 func (proj *Project) Repos() map[string]*repo.Repo {
 	return proj.repos
 }
@@ -285,6 +314,27 @@ func (proj *Project) Warnings() []string {
 	return proj.warnings
 }
 
+// ScanWarnings returns the problems encountered while scanning the project's
+// repos for packages, including ones not reported by Warnings().
+func (proj *Project) ScanWarnings() []pkg.ScanWarning {
+	return proj.scanWarnings
+}
+
+// ExpandAlias translates a user-defined alias of the form "@<alias>" into the
+// package name it stands for.  Aliases are defined in the `project.aliases`
+// map of `project.yml`.  Names that are not aliases are returned unchanged.
+func (proj *Project) ExpandAlias(name string) string {
+	if !strings.HasPrefix(name, "@") || strings.Contains(name, "/") {
+		return name
+	}
+
+	if expanded, ok := proj.aliases[strings.TrimPrefix(name, "@")]; ok {
+		return expanded
+	}
+
+	return name
+}
+
 // Selects repositories from the global state that satisfy the specified
 // predicate.
 func (proj *Project) SelectRepos(pred func(r *repo.Repo) bool) []*repo.Repo {
@@ -636,6 +686,20 @@ func (proj *Project) loadConfig(download bool) error {
 		}
 	}
 
+	proj.aliases, err = yc.GetValStringMapString("project.aliases", nil)
+	util.OneTimeWarningError(err)
+
+	proj.targetInlineSyscfg, err = yc.GetValBoolDflt(
+		"project.target_inline_syscfg", nil, false)
+	util.OneTimeWarningError(err)
+
+	promptDflt, err := yc.GetValString("project.prompt_default", nil)
+	util.OneTimeWarningError(err)
+	if promptDflt != "" {
+		proj.promptDefault, err = newtutil.ParsePromptDefault(promptDflt)
+		util.OneTimeWarningError(err)
+	}
+
 	ignoreDirs, err := yc.GetValStringSlice("project.ignore_dirs", nil)
 	util.OneTimeWarningError(err)
 	for _, ignDir := range ignoreDirs {
@@ -755,6 +819,44 @@ func (proj *Project) ResolvePath(
 	}
 }
 
+// DanglingLinks checks that each transient package in the project links to a
+// package that exists.  It returns a description of every dangling link,
+// sorted by transient package path.  A link cannot be checked while an
+// individual package is loaded because the full package map is not yet
+// available.
+func (proj *Project) DanglingLinks() []string {
+	var dangling []string
+
+	for _, pkgMap := range proj.packages {
+		for _, pkgItf := range *pkgMap {
+			lpkg, ok := pkgItf.(*pkg.LocalPackage)
+			if !ok || lpkg.Type() != pkg.PACKAGE_TYPE_TRANSIENT {
+				continue
+			}
+
+			if _, err := proj.ResolvePackage(
+				lpkg.Repo(), lpkg.LinkedName()); err == nil {
+
+				continue
+			}
+
+			linkPath, err := proj.ResolvePath(lpkg.Repo().Path(),
+				lpkg.LinkedName())
+			if err != nil {
+				linkPath = err.Error()
+			}
+
+			dangling = append(dangling, fmt.Sprintf(
+				"%s: pkg.link=%s does not refer to a package (path=%s)",
+				lpkg.BasePath(), lpkg.LinkedName(), linkPath))
+		}
+	}
+
+	sort.Strings(dangling)
+
+	return dangling
+}
+
 func findProjectDir(dir string) (string, error) {
 	for {
 		projFile := path.Clean(dir) + "/" + PROJECT_FILE_NAME
@@ -783,12 +885,14 @@ func (proj *Project) loadPackageList() error {
 	// packages / store them in the project package list.
 	repos := proj.Repos()
 	for name, repo := range repos {
-		list, warnings, err := pkg.ReadLocalPackages(repo, repo.Path())
+		list, warnings, err := pkg.ScanLocalPackages(repo, repo.Path())
 		if err == nil {
 			proj.packages[name] = list
 		}
 
-		proj.warnings = append(proj.warnings, warnings...)
+		proj.scanWarnings = append(proj.scanWarnings, warnings...)
+		proj.warnings = append(proj.warnings,
+			pkg.ScanWarningStrings(warnings)...)
 	}
 
 	return nil
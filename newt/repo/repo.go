@@ -135,12 +135,22 @@ func (repo *Repo) FilteredSearchList(
 		return list, warning
 	}
 
+	// Record the directory being searched.  Its children are recorded below
+	// before they are searched, but the top-level directory must be added
+	// explicitly; otherwise a symlink to it would be followed.
+	if realPath, err := filepath.EvalSymlinks(path); err == nil {
+		searchedMap[realPath] = struct{}{}
+	}
+
 	for _, dirEnt := range dirList {
-		// Resolve symbolic links.
+		// Resolve symbolic links.  Warn about and skip dangling links rather
+		// than abandoning the rest of the directory.
 		entPath := filepath.Join(path, dirEnt.Name())
 		entry, err := os.Stat(entPath)
 		if err != nil {
-			return nil, util.ChildNewtError(err)
+			util.OneTimeWarning("Skipping unreadable directory entry %s: %s",
+				entPath, err.Error())
+			continue
 		}
 
 		name := entry.Name()
@@ -91,10 +91,21 @@ type ResolveDep struct {
 	ApiExprMap parse.ExprMap
 }
 
+// An optional `pkg.deps` entry.  It only becomes a dependency if the dependee
+// is required by some other package.
+type resolveOptDep struct {
+	lpkg *pkg.LocalPackage
+	expr *parse.Node
+}
+
 type ResolvePackage struct {
 	Lpkg *pkg.LocalPackage
 	Deps map[*ResolvePackage]*ResolveDep
 
+	// Optional dependencies discovered during the last load of this package's
+	// dependency list.
+	optDeps []resolveOptDep
+
 	Apis parse.ExprMap
 
 	// Keeps track of API requirements and whether they are satisfied.
@@ -500,6 +511,7 @@ func (r *Resolver) loadDepsForPkg(rpkg *ResolvePackage) (bool, error) {
 
 	oldDeps := rpkg.Deps
 	rpkg.Deps = make(map[*ResolvePackage]*ResolveDep, len(oldDeps))
+	rpkg.optDeps = nil
 	for expr, depNames := range depEm {
 		for _, depName := range depNames {
 			newDep, err := pkg.NewDependency(rpkg.Lpkg.Repo(), depName)
@@ -507,6 +519,14 @@ func (r *Resolver) loadDepsForPkg(rpkg *ResolvePackage) (bool, error) {
 				return false, err
 			}
 
+			// An optional dependency on a package that doesn't exist (e.g.,
+			// one in a repo that isn't installed) is ignored.
+			if newDep.Optional &&
+				project.GetProject().ResolveDependency(newDep) == nil {
+
+				continue
+			}
+
 			lpkg, err := r.resolveDep(newDep, depender)
 			if err != nil {
 				return false, err
@@ -526,6 +546,11 @@ func (r *Resolver) loadDepsForPkg(rpkg *ResolvePackage) (bool, error) {
 					lpkg.FullName(), pkg.PackageTypeNames[lpkg.Type()]))
 			}
 
+			if newDep.Optional {
+				rpkg.optDeps = append(rpkg.optDeps, resolveOptDep{lpkg, expr})
+				continue
+			}
+
 			depRpkg, _ := r.addPkg(lpkg)
 			rpkg.AddDep(depRpkg, expr)
 		}
@@ -794,13 +819,13 @@ func (r *Resolver) pruneImposters() (bool, error) {
 	jobsCh := make(chan *ResolvePackage, len(r.pkgMap))
 	defer close(jobsCh)
 
-	stopCh := make(chan struct{}, newtutil.NewtNumJobs)
+	stopCh := make(chan struct{}, newtutil.NewtResolveJobs)
 	defer close(stopCh)
 
 	resultsCh := make(chan *ResolvePackage, len(r.pkgMap))
 	defer close(resultsCh)
 
-	errCh := make(chan error, newtutil.NewtNumJobs)
+	errCh := make(chan error, newtutil.NewtResolveJobs)
 	defer close(errCh)
 
 	seedMap := map[*pkg.LocalPackage]struct{}{}
@@ -816,12 +841,12 @@ func (r *Resolver) pruneImposters() (bool, error) {
 	}
 
 	// Iterate through all packages with a collection of go routines.
-	for i := 0; i < newtutil.NewtNumJobs; i++ {
+	for i := 0; i < newtutil.NewtResolveJobs; i++ {
 		go r.detectImpostersWorker(jobsCh, stopCh, resultsCh, errCh)
 	}
 
 	// Collect errors from each routine.  Abort on first error.
-	for i := 0; i < newtutil.NewtNumJobs; i++ {
+	for i := 0; i < newtutil.NewtResolveJobs; i++ {
 		if err := <-errCh; err != nil {
 			return false, err
 		}
@@ -974,6 +999,19 @@ func (r *Resolver) resolveConfigPriorities() {
 	}
 }
 
+// Adds the optional dependencies of all packages whose dependees are already
+// in the resolver.  This must be called after the hard dependencies have been
+// fully resolved; optional dependencies never add packages to the build.
+func (r *Resolver) resolveOptionalDeps() {
+	for _, rpkg := range r.pkgMap {
+		for _, od := range rpkg.optDeps {
+			if depRpkg := r.pkgMap[od.lpkg]; depRpkg != nil {
+				rpkg.AddDep(depRpkg, od.expr)
+			}
+		}
+	}
+}
+
 // Fully resolves all hard dependencies (i.e., packages listed in `pkg.deps`;
 // not API dependencies).
 func (r *Resolver) resolveHardDeps() error {
@@ -1003,6 +1041,7 @@ func (r *Resolver) resolveDeps() ([]*ResolvePackage, error) {
 	if err := r.resolveHardDeps(); err != nil {
 		return nil, err
 	}
+	r.resolveOptionalDeps()
 
 	// Now that the final set of packages is known, determine which ones
 	// satisfy each required API.
@@ -1054,6 +1093,7 @@ func (r *Resolver) resolveDepsAndCfg() error {
 			break
 		}
 	}
+	r.resolveOptionalDeps()
 
 	// Now that the final set of packages is known, determine which ones
 	// satisfy each required API.
@@ -1362,6 +1402,16 @@ func (res *Resolution) ExperimentalWarning() []string {
 	return res.Cfg.ExperimentalWarning()
 }
 
+// StageConflictWarning returns a warning line for each set of external
+// commands that share a stage number.
+func (res *Resolution) StageConflictWarning() []string {
+	lines := res.PreBuildCmdCfg.ConflictWarning()
+	lines = append(lines, res.PreLinkCmdCfg.ConflictWarning()...)
+	lines = append(lines, res.PostLinkCmdCfg.ConflictWarning()...)
+
+	return lines
+}
+
 func LogTransientWarning(lpkg *pkg.LocalPackage) {
 	if lpkg.Type() == pkg.PACKAGE_TYPE_TRANSIENT {
 		log.Warnf("Transient package %s used, update configuration "+
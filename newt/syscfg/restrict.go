@@ -342,10 +342,42 @@ func (cfg *Cfg) restrictionMet(
 		return val
 
 	default:
-		panic("Invalid restriction code: " + string(r.Code))
+		panic(fmt.Sprintf("Invalid restriction code: %d", r.Code))
 	}
 }
 
+// CheckValueRange verifies that a setting's value satisfies the setting's
+// range restrictions.  The value, and any other setting referenced by a range
+// expression, is read from `settings`.  Non-integer values are not checked;
+// CheckValueType reports those.
+func (cfg *Cfg) CheckValueRange(name string, settings *cfgv.Settings) error {
+	entry, ok := cfg.Settings[name]
+	if !ok {
+		return nil
+	}
+
+	value := strings.TrimSpace(settings.Get(name))
+	if _, ok := util.AtoiNoOctTry(value); !ok {
+		return nil
+	}
+
+	for _, r := range entry.Restrictions {
+		if r.Code != CFG_RESTRICTION_CODE_RANGE {
+			continue
+		}
+
+		// An illegal expression is reported when the target is built.
+		met, err := parse.ParseAndEval(r.createRangeExpr(), settings)
+		if err == nil && !met {
+			return util.FmtNewtError(
+				"setting %s must be in range [%s] (value=%s)",
+				name, r.Expr, value)
+		}
+	}
+
+	return nil
+}
+
 func createRangeRestriction(baseSetting string, expr string) (CfgRestriction, error) {
 	r := CfgRestriction{
 		BaseSetting: baseSetting,
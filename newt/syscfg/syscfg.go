@@ -80,6 +80,7 @@ const SYSCFG_PRIO_ANY = "any"
 const SYSCFG_TASK_PRIO_MAX = 0xef
 
 var cfgRefRe = regexp.MustCompile("MYNEWT_VAL\\((\\w+)\\)")
+var cfgInterpRe = regexp.MustCompile("\\$\\{(\\w+)\\}")
 var cfgChoiceValRe = regexp.MustCompile("^[A-Za-z0-9_]+$")
 var cfgPkgRepoName = regexp.MustCompile("^@([A-Za-z0-9-_]+)/")
 var cfgPkgIllegalChar = regexp.MustCompile("[^A-Za-z0-9_]")
@@ -162,6 +163,9 @@ type Cfg struct {
 
 	// Unresolved value references
 	UnresolvedValueRefs map[string]struct{}
+
+	// Settings whose `${SETTING}` references form a cycle.
+	CircularValueRefs map[string]struct{}
 }
 
 func NewCfg() Cfg {
@@ -180,6 +184,7 @@ func NewCfg() Cfg {
 		Consts:              map[string]struct{}{},
 		Experimental:        map[string]struct{}{},
 		UnresolvedValueRefs: map[string]struct{}{},
+		CircularValueRefs:   map[string]struct{}{},
 	}
 }
 
@@ -242,7 +247,80 @@ func (cfg *Cfg) AddInjectedSettings() {
 	}
 }
 
+// Expands the `${SETTING}` references in a setting's value, e.g.,
+// "${MSYS_1_BLOCK_SIZE}".  Referenced settings are expanded first.  A
+// reference to an undefined setting is recorded as an unresolved value
+// reference, and a chain of references that leads back to the setting is
+// recorded as a circular reference; in both cases the setting's value is set
+// to 0.  A setting that references a circular setting, directly or
+// indirectly, is circular as well.  Returns false if the setting is circular.
+func (cfg *Cfg) interpolateValue(name string,
+	visiting map[string]struct{}) bool {
+
+	if _, ok := cfg.CircularValueRefs[name]; ok {
+		return false
+	}
+
+	entry := cfg.Settings[name]
+	if !cfgInterpRe.MatchString(entry.Value) {
+		return true
+	}
+
+	if _, ok := visiting[name]; ok {
+		return false
+	}
+	visiting[name] = struct{}{}
+	defer delete(visiting, name)
+
+	ok := true
+	undefined := ""
+	expand := func(ref string) string {
+		refName := cfgInterpRe.FindStringSubmatch(ref)[1]
+		if _, exists := cfg.Settings[refName]; !exists {
+			undefined = refName
+			return ref
+		}
+
+		if !cfg.interpolateValue(refName, visiting) {
+			ok = false
+			return ref
+		}
+
+		return cfg.Settings[refName].Value
+	}
+	val := cfgInterpRe.ReplaceAllStringFunc(entry.Value, expand)
+
+	entry = cfg.Settings[name]
+	switch {
+	case !ok:
+		cfg.CircularValueRefs[name] = struct{}{}
+		entry.Value = "0"
+	case undefined != "":
+		cfg.UnresolvedValueRefs[name] = struct{}{}
+		entry.ValueRefName = undefined
+		entry.Value = "0"
+	default:
+		entry.Value = val
+	}
+	cfg.Settings[name] = entry
+
+	return ok
+}
+
 func (cfg *Cfg) ResolveValueRefs() {
+	// Process settings in a consistent order so that the results do not
+	// depend on map iteration.
+	names := make([]string, 0, len(cfg.Settings))
+	for k, _ := range cfg.Settings {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	visiting := map[string]struct{}{}
+	for _, k := range names {
+		cfg.interpolateValue(k, visiting)
+	}
+
 	for k, entry := range cfg.Settings {
 		refName, val, err := cfg.ExpandRef(strings.TrimSpace(entry.Value))
 		if err != nil {
@@ -308,6 +386,66 @@ func (entry *CfgEntry) IsTrue() bool {
 	return parse.ValueIsTrue(entry.Value)
 }
 
+// CheckValueType verifies that the specified value is compatible with the
+// setting's definition.  Settings are untyped, so the expected type is
+// inferred from the definition:
+//     * task and interrupt priorities must be integers ("any" is also
+//       accepted for task priorities).
+//     * settings with choices must be set to one of the valid choices.
+//     * settings with an integer default must be set to an integer.
+//
+// Values that reference another setting (`MYNEWT_VAL(...)` or `${...}`) and
+// empty values are not checked.
+func (entry *CfgEntry) CheckValueType(value string) error {
+	value = strings.TrimSpace(value)
+	if value == "" || ResolveValueRefName(value) != "" ||
+		cfgInterpRe.MatchString(value) {
+		return nil
+	}
+
+	_, isInt := util.AtoiNoOctTry(value)
+
+	switch entry.SettingType {
+	case CFG_SETTING_TYPE_TASK_PRIO:
+		if !isInt && value != SYSCFG_PRIO_ANY {
+			return util.FmtNewtError(
+				"setting %s is a task priority; value must be an integer "+
+					"or \"%s\" (value=%s)", entry.Name, SYSCFG_PRIO_ANY, value)
+		}
+		return nil
+
+	case CFG_SETTING_TYPE_INTERRUPT_PRIO:
+		if !isInt {
+			return util.FmtNewtError(
+				"setting %s is an interrupt priority; value must be an "+
+					"integer (value=%s)", entry.Name, value)
+		}
+		return nil
+	}
+
+	if len(entry.ValidChoices) > 0 {
+		for _, choice := range entry.ValidChoices {
+			if strings.ToLower(choice) == strings.ToLower(value) {
+				return nil
+			}
+		}
+		return util.FmtNewtError(
+			"setting %s must be one of [%s] (value=%s)", entry.Name,
+			strings.Join(entry.ValidChoices, ", "), value)
+	}
+
+	if len(entry.History) > 0 {
+		dflt := strings.TrimSpace(entry.History[0].Value)
+		if _, ok := util.AtoiNoOctTry(dflt); ok && !isInt {
+			return util.FmtNewtError(
+				"setting %s has an integer default (%s); value must be an "+
+					"integer (value=%s)", entry.Name, dflt, value)
+		}
+	}
+
+	return nil
+}
+
 func (entry *CfgEntry) appendValue(lpkg *pkg.LocalPackage, value interface{}) {
 	strval := stringValue(value)
 	point := CfgPoint{Value: strval, Source: lpkg}
@@ -670,6 +808,53 @@ func (cfg *Cfg) readDefsOnce(lpkg *pkg.LocalPackage,
 	return nil
 }
 
+// SettingDefault is the default value a package assigns to a setting it
+// defines.
+type SettingDefault struct {
+	Name  string
+	Value string
+	Lpkg  *pkg.LocalPackage
+}
+
+// ReadDefaults returns the default values of the settings defined in a
+// package's "syscfg.defs" section, sorted by setting name.  Only unconditional
+// definitions are read, since no syscfg is available to evaluate conditions.
+// Settings without a default value are omitted.
+func ReadDefaults(lpkg *pkg.LocalPackage) []SettingDefault {
+	defs, err := lpkg.SyscfgY.GetValStringMap("syscfg.defs", nil)
+	util.OneTimeWarningError(err)
+
+	sds := []SettingDefault{}
+	for k, v := range defs {
+		var val interface{}
+
+		switch v.(type) {
+		case map[interface{}]interface{}:
+			dflt, ok := v.(map[interface{}]interface{})["value"]
+			if !ok {
+				continue
+			}
+			val = dflt
+		case int, string:
+			val = v
+		default:
+			continue
+		}
+
+		sds = append(sds, SettingDefault{
+			Name:  k,
+			Value: stringValue(val),
+			Lpkg:  lpkg,
+		})
+	}
+
+	sort.Slice(sds, func(i int, j int) bool {
+		return sds[i].Name < sds[j].Name
+	})
+
+	return sds
+}
+
 // Records an orphan override error (override of undefined setting).
 func (cfg *Cfg) addOrphan(settingName string, value string,
 	lpkg *pkg.LocalPackage) {
@@ -834,6 +1019,12 @@ func (cfg *Cfg) settingsOfType(typ CfgSettingType) []CfgEntry {
 func (cfg *Cfg) detectViolations() {
 	settings := cfg.SettingValues()
 	for _, entry := range cfg.Settings {
+		// A circular setting's value is meaningless; its restrictions can't
+		// be evaluated.
+		if _, ok := cfg.CircularValueRefs[entry.Name]; ok {
+			continue
+		}
+
 		var sv []CfgRestriction
 		for _, r := range entry.Restrictions {
 			if !cfg.restrictionMet(r, settings) {
@@ -931,7 +1122,7 @@ func (cfg *Cfg) flashConflictErrorText(conflict CfgFlashConflict) string {
 			entry.Value)
 
 	default:
-		panic("Invalid flash conflict code: " + string(conflict.Code))
+		panic(fmt.Sprintf("Invalid flash conflict code: %d", conflict.Code))
 	}
 }
 
@@ -1091,6 +1282,22 @@ func (cfg *Cfg) ErrorText() string {
 		}
 	}
 
+	// Circular value references
+	if len(cfg.CircularValueRefs) > 0 {
+		names := make([]string, 0, len(cfg.CircularValueRefs))
+		for name, _ := range cfg.CircularValueRefs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		str += "Circular value references:\n"
+		for _, name := range names {
+			entry := cfg.Settings[name]
+			str += "    " + fmt.Sprintf("%s\n", name)
+			historyMap[name] = entry.History
+		}
+	}
+
 	if len(historyMap) > 0 {
 		str += "\n" + historyText(historyMap)
 	}
@@ -1395,7 +1602,7 @@ func calcPriorities(cfg Cfg, settingType CfgSettingType, max int,
 		greatest++
 		if greatest > max {
 			return util.FmtNewtError("could not assign 'any' priority: "+
-				"value too great (> %d); setting=%s value=%s pkg=%s",
+				"value too great (> %d); setting=%s value=%d pkg=%s",
 				max, name, greatest,
 				mostRecentPoint(entry).Name())
 		}
@@ -1622,6 +1829,13 @@ func write(cfg Cfg, lpkgs []*pkg.LocalPackage, w io.Writer) {
 	fmt.Fprintf(w, "#endif\n")
 }
 
+// WriteHeader writes the contents of the generated syscfg header file to the
+// specified writer.  The output is identical to the header that newt generates
+// during a build.
+func WriteHeader(cfg Cfg, lpkgs []*pkg.LocalPackage, w io.Writer) {
+	write(cfg, lpkgs, w)
+}
+
 func EnsureWritten(cfg Cfg, includeDir string, lpkgs []*pkg.LocalPackage) error {
 	// XXX: Detect these problems at error text generation time.
 	if err := calcPriorities(cfg, CFG_SETTING_TYPE_TASK_PRIO,
@@ -1657,6 +1871,93 @@ func EnsureWritten(cfg Cfg, includeDir string, lpkgs []*pkg.LocalPackage) error
 	return nil
 }
 
+// Splits a string at each occurrence of the specified separator that is not
+// enclosed in double quotes.  A backslash escapes the following character.
+// At most `n` fields are returned; n < 0 means no limit.
+func splitUnquoted(str string, sep rune, n int) []string {
+	fields := []string{}
+
+	inQuotes := false
+	escaped := false
+	start := 0
+	for i, c := range str {
+		if escaped {
+			escaped = false
+			continue
+		}
+
+		switch {
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == sep && !inQuotes:
+			if n < 0 || len(fields) < n-1 {
+				fields = append(fields, str[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(fields, str[start:])
+}
+
+// Removes the backslashes that escape characters outside double quotes.
+// Text inside double quotes is returned unchanged, escapes included.
+func unescapeUnquoted(str string) string {
+	var b bytes.Buffer
+
+	inQuotes := false
+	escaped := false
+	for _, c := range str {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && !inQuotes:
+			escaped = true
+			continue
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// Escapes the colons and backslashes outside double quotes in a setting value
+// so that KeyValueFromStr parses it as a single value.  This is the inverse of
+// unescapeUnquoted().
+func escapeUnquoted(str string) string {
+	var b bytes.Buffer
+
+	inQuotes := false
+	escaped := false
+	for _, c := range str {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes && c == '\\':
+			escaped = true
+		case !inQuotes && (c == ':' || c == '\\'):
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+
+	return b.String()
+}
+
+// KeyValueFromStr parses a string of syscfg settings of the form
+// "<name>[=<value>][:<name>[=<value>]...]".  A setting without a value is
+// assigned 1.  Colons and equals signs inside double quotes are not treated
+// as delimiters, so string values (e.g., `BANNER="a: b"`) are preserved
+// intact, quotes included.  Outside double quotes, a backslash escapes the
+// following character (e.g., `PATH=a\:b`) and is removed.
 func KeyValueFromStr(str string) (map[string]string, error) {
 	vals := map[string]string{}
 
@@ -1665,7 +1966,7 @@ func KeyValueFromStr(str string) (map[string]string, error) {
 	}
 
 	// Separate syscfg vals are delimited by ':'.
-	fields := strings.Split(str, ":")
+	fields := splitUnquoted(str, ':', -1)
 
 	// Key-value pairs are delimited by '='.  If no '=' is present, assume the
 	// string is the key name and the value is 1.
@@ -1675,12 +1976,40 @@ func KeyValueFromStr(str string) (map[string]string, error) {
 				"Invalid setting name \"%s\"; must not be a number", f)
 		}
 
-		kv := strings.SplitN(f, "=", 2)
+		kv := splitUnquoted(f, '=', 2)
 		switch len(kv) {
 		case 1:
-			vals[f] = "1"
+			vals[unescapeUnquoted(f)] = "1"
 		case 2:
-			vals[kv[0]] = kv[1]
+			vals[unescapeUnquoted(kv[0])] = unescapeUnquoted(kv[1])
+		}
+	}
+
+	return vals, nil
+}
+
+// KeyValueFromFile reads syscfg settings from a text file.  Each line of the
+// file contains settings in the same form accepted by `KeyValueFromStr`.
+// Blank lines and lines starting with '#' are ignored.
+func KeyValueFromFile(path string) (map[string]string, error) {
+	lines, err := util.ReadLines(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := map[string]string{}
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineVals, err := KeyValueFromStr(line)
+		if err != nil {
+			return nil, util.PreNewtError(err, "%s:%d", path, i+1)
+		}
+		for k, v := range lineVals {
+			vals[k] = v
 		}
 	}
 
@@ -1701,7 +2030,7 @@ func KeyValueToStr(syscfgKv map[string]string) string {
 			str += ":"
 		}
 
-		str += fmt.Sprintf("%s=%s", name, syscfgKv[name])
+		str += fmt.Sprintf("%s=%s", name, escapeUnquoted(syscfgKv[name]))
 	}
 
 	return str
@@ -0,0 +1,356 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package syscfg
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/config"
+	"mynewt.apache.org/newt/newt/pkg"
+	"mynewt.apache.org/newt/util"
+)
+
+// Creates a setting entry with the specified default value.
+func testEntry(name string, settingType CfgSettingType, dflt string,
+	choices []string) CfgEntry {
+
+	return CfgEntry{
+		Name:         name,
+		Value:        dflt,
+		SettingType:  settingType,
+		ValidChoices: choices,
+		History:      []CfgPoint{{Value: dflt}},
+	}
+}
+
+func TestCheckValueType(t *testing.T) {
+	taskPrio := testEntry("TASK_PRIO", CFG_SETTING_TYPE_TASK_PRIO, "any", nil)
+	irqPrio := testEntry("IRQ_PRIO", CFG_SETTING_TYPE_INTERRUPT_PRIO, "3",
+		nil)
+	choice := testEntry("MODE", CFG_SETTING_TYPE_RAW, "fast",
+		[]string{"fast", "slow"})
+	intVal := testEntry("BUF_SIZE", CFG_SETTING_TYPE_RAW, "128", nil)
+	timerNum := testEntry("OS_CPUTIME_TIMER_NUM", CFG_SETTING_TYPE_RAW, "0",
+		nil)
+	maxConns := testEntry("BLE_MAX_CONNECTIONS", CFG_SETTING_TYPE_RAW, "1",
+		nil)
+	str := testEntry("BANNER", CFG_SETTING_TYPE_RAW, `"hello"`, nil)
+
+	tests := []struct {
+		entry CfgEntry
+		value string
+		ok    bool
+	}{
+		{taskPrio, "5", true},
+		{taskPrio, "any", true},
+		{taskPrio, "high", false},
+		{irqPrio, "0x10", true},
+		{irqPrio, "any", false},
+		{choice, "slow", true},
+		{choice, "SLOW", true},
+		{choice, "medium", false},
+		{intVal, "256", true},
+		{intVal, "0x100", true},
+		{intVal, "big", false},
+		{intVal, "MYNEWT_VAL(OTHER)", true},
+		{intVal, "${OTHER}", true},
+		{intVal, "", true},
+		{timerNum, "2", true},
+		{timerNum, "1", true},
+		{timerNum, "two", false},
+		{maxConns, "8", true},
+		{str, `"bye"`, true},
+		{str, "7", true},
+	}
+
+	for _, test := range tests {
+		err := test.entry.CheckValueType(test.value)
+		if test.ok && err != nil {
+			t.Errorf("%s=%s: unexpected error: %s", test.entry.Name,
+				test.value, err.Error())
+		} else if !test.ok && err == nil {
+			t.Errorf("%s=%s: expected error", test.entry.Name, test.value)
+		}
+	}
+}
+
+func TestKeyValueFromStr(t *testing.T) {
+	tests := []struct {
+		str  string
+		want map[string]string
+	}{
+		{"", map[string]string{}},
+		{"A", map[string]string{"A": "1"}},
+		{"A=1:B=2", map[string]string{"A": "1", "B": "2"}},
+		{`BANNER="Hello World"`,
+			map[string]string{"BANNER": `"Hello World"`}},
+		{`BANNER="a: b":C=3`,
+			map[string]string{"BANNER": `"a: b"`, "C": "3"}},
+		{`EXPR="x=y":C`, map[string]string{"EXPR": `"x=y"`, "C": "1"}},
+		{"EXPR=x=y", map[string]string{"EXPR": "x=y"}},
+		{`QUOTE="say \"a:b\""`,
+			map[string]string{"QUOTE": `"say \"a:b\""`}},
+		{`PATH=a\:b:C=3`, map[string]string{"PATH": "a:b", "C": "3"}},
+		{`PATH=a\\b`, map[string]string{"PATH": `a\b`}},
+	}
+
+	for _, test := range tests {
+		got, err := KeyValueFromStr(test.str)
+		if err != nil {
+			t.Errorf("KeyValueFromStr(%s): unexpected error: %s", test.str,
+				err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("KeyValueFromStr(%s) = %v; want %v", test.str, got,
+				test.want)
+		}
+	}
+
+	if _, err := KeyValueFromStr("A=1:5"); err == nil {
+		t.Errorf("KeyValueFromStr(A=1:5): expected error")
+	}
+}
+
+// Values that KeyValueToStr() produces must parse back to the same values.
+func TestKeyValueStrRoundTrip(t *testing.T) {
+	vals := map[string]string{
+		"BANNER": `"Hello: World"`,
+		"QUOTE":  `"a\"b:c"`,
+		"PATH":   "a:b",
+		"WIN":    `c:\dir`,
+		"EXPR":   "x=y",
+		"SPACE":  "1 + 2",
+		"NUM":    "7",
+	}
+
+	str := KeyValueToStr(vals)
+	got, err := KeyValueFromStr(str)
+	if err != nil {
+		t.Fatalf("KeyValueFromStr(%s): unexpected error: %s", str,
+			err.Error())
+	}
+	if !reflect.DeepEqual(got, vals) {
+		t.Errorf("KeyValueFromStr(%s) = %v; want %v", str, got, vals)
+	}
+}
+
+// Values parsed from a string must survive being saved to and read from a
+// syscfg.yml file.
+func TestKeyValueSaveSyscfg(t *testing.T) {
+	vals, err := KeyValueFromStr(
+		`BANNER="Hello: World":EXPR="x=y":SPACE=1 + 2:PATH=a\:b`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "newt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lpkg := pkg.NewLocalPackage(nil, dir)
+	lpkg.SyscfgY.Replace("syscfg.vals", util.StringMapStringToItfMapItf(vals))
+	if err := lpkg.SaveSyscfg(); err != nil {
+		t.Fatal(err)
+	}
+
+	yc, err := config.ReadFile(lpkg.SyscfgYamlPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := yc.GetValStringMapString("syscfg.vals", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, vals) {
+		t.Errorf("saved syscfg.vals = %v; want %v", got, vals)
+	}
+}
+
+// Creates a configuration containing raw settings with the specified values.
+func testCfg(vals map[string]string) Cfg {
+	cfg := NewCfg()
+	for name, val := range vals {
+		cfg.Settings[name] = testEntry(name, CFG_SETTING_TYPE_RAW, val, nil)
+	}
+
+	return cfg
+}
+
+func TestResolveValueRefs(t *testing.T) {
+	tests := []struct {
+		name       string
+		vals       map[string]string
+		want       map[string]string
+		unresolved []string
+		circular   []string
+	}{
+		{
+			name: "simple",
+			vals: map[string]string{
+				"A": "${B}",
+				"B": "10",
+				"C": "(${B} * 2)",
+			},
+			want: map[string]string{
+				"A": "10",
+				"B": "10",
+				"C": "(10 * 2)",
+			},
+		},
+		{
+			name: "chained",
+			vals: map[string]string{
+				"A": "${B}",
+				"B": "${C}",
+				"C": "5",
+			},
+			want: map[string]string{
+				"A": "5",
+				"B": "5",
+				"C": "5",
+			},
+		},
+		{
+			name: "MYNEWT_VAL",
+			vals: map[string]string{
+				"A": "MYNEWT_VAL(B)",
+				"B": "7",
+			},
+			want: map[string]string{
+				"A": "7",
+				"B": "7",
+			},
+		},
+		{
+			name: "cycle",
+			vals: map[string]string{
+				"A": "${B}",
+				"B": "${A}",
+			},
+			want: map[string]string{
+				"A": "0",
+				"B": "0",
+			},
+			circular: []string{"A", "B"},
+		},
+		{
+			// A setting that references a cycle is circular as well.
+			name: "reference to cycle",
+			vals: map[string]string{
+				"A": "${B}",
+				"B": "${A}",
+				"C": "${A}",
+			},
+			want: map[string]string{
+				"A": "0",
+				"B": "0",
+				"C": "0",
+			},
+			circular: []string{"A", "B", "C"},
+		},
+		{
+			name: "undefined",
+			vals: map[string]string{
+				"A": "${NOPE}",
+				"B": "MYNEWT_VAL(NOPE)",
+				"C": "3",
+			},
+			want: map[string]string{
+				"A": "0",
+				"B": "0",
+				"C": "3",
+			},
+			unresolved: []string{"A", "B"},
+		},
+	}
+
+	keys := func(m map[string]struct{}) []string {
+		names := []string{}
+		for name, _ := range m {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	for _, test := range tests {
+		cfg := testCfg(test.vals)
+		cfg.ResolveValueRefs()
+
+		for name, want := range test.want {
+			if got := cfg.Settings[name].Value; got != want {
+				t.Errorf("%s: %s = %q; want %q", test.name, name, got, want)
+			}
+		}
+
+		unresolved := keys(cfg.UnresolvedValueRefs)
+		if len(unresolved) != 0 || len(test.unresolved) != 0 {
+			if !reflect.DeepEqual(unresolved, test.unresolved) {
+				t.Errorf("%s: unresolved = %v; want %v", test.name,
+					unresolved, test.unresolved)
+			}
+		}
+
+		circular := keys(cfg.CircularValueRefs)
+		if len(circular) != 0 || len(test.circular) != 0 {
+			if !reflect.DeepEqual(circular, test.circular) {
+				t.Errorf("%s: circular = %v; want %v", test.name,
+					circular, test.circular)
+			}
+		}
+	}
+}
+
+// Verifies that the error reported for a reference cycle does not depend on
+// map iteration order.
+func TestResolveValueRefsCycleErrorText(t *testing.T) {
+	vals := map[string]string{
+		"A": "${B}",
+		"B": "${A}",
+		"C": "1",
+	}
+
+	var first string
+	for i := 0; i < 20; i++ {
+		cfg := testCfg(vals)
+		cfg.ResolveValueRefs()
+
+		text := cfg.ErrorText()
+		if i == 0 {
+			first = text
+			want := "Circular value references:\n    A\n    B\n"
+			if !strings.Contains(text, want) {
+				t.Fatalf("error text:\n%s\nwant it to contain:\n%s", text,
+					want)
+			}
+		} else if text != first {
+			t.Fatalf("error text differs between runs:\n%s\nvs.\n%s",
+				first, text)
+		}
+	}
+}
@@ -20,16 +20,22 @@
 package target
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"mynewt.apache.org/newt/newt/config"
 	"mynewt.apache.org/newt/newt/interfaces"
 	"mynewt.apache.org/newt/newt/pkg"
 	"mynewt.apache.org/newt/newt/project"
 	"mynewt.apache.org/newt/newt/repo"
+	"mynewt.apache.org/newt/newt/syscfg"
 	"mynewt.apache.org/newt/newt/ycfg"
 	"mynewt.apache.org/newt/util"
 )
@@ -51,6 +57,12 @@ type Target struct {
 	KeyFile      string
 	PkgProfiles  map[string]string
 
+	// Whether the target is protected from modification (pkg.locked).
+	locked bool
+
+	// Whether the target.yml file contains the target's syscfg settings.
+	inlineSyscfg bool
+
 	// target.yml configuration structure
 	TargetY ycfg.YCfg
 }
@@ -84,6 +96,81 @@ func (target *Target) TargetYamlPath() string {
 	return fmt.Sprintf("%s/%s", target.basePkg.BasePath(), TARGET_FILENAME)
 }
 
+// Target variables that can be overridden with environment variables.
+var envOverrideVars = []string{
+	"app",
+	"bsp",
+	"loader",
+	"build_profile",
+	"header_size",
+	"key_file",
+}
+
+// Returns the prefix of the environment variables that override the
+// variables of the specified target, e.g., NEWT_TARGET_TARGETS_MY_BLINKY_ for
+// "targets/my-blinky".  The full target name is used, uppercased, with
+// non-alphanumeric characters replaced by '_'.
+func envOverridePrefix(targetName string) string {
+	clean := strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, strings.ToUpper(targetName))
+
+	return "NEWT_TARGET_" + clean + "_"
+}
+
+// Returns the name of the environment variable that overrides the specified
+// target variable, e.g., NEWT_TARGET_TARGETS_MY_BLINKY_BUILD_PROFILE for the
+// build_profile variable of "targets/my-blinky".
+func envOverrideName(targetName string, varName string) string {
+	return envOverridePrefix(targetName) + strings.ToUpper(varName)
+}
+
+// Reads a target variable.  If the corresponding environment variable (see
+// envOverrideName) is set, its value takes precedence over target.yml.
+// Overrides only affect the in-memory target; they are never saved.
+func (target *Target) readVar(yc ycfg.YCfg, varName string) (string, error) {
+	envName := envOverrideName(target.basePkg.Name(), varName)
+	if val, ok := os.LookupEnv(envName); ok {
+		util.StatusMessage(util.VERBOSITY_VERBOSE,
+			"Target %s: %s=%s (from %s)\n",
+			target.basePkg.FullName(), varName, val, envName)
+		return val, nil
+	}
+
+	return yc.GetValString("target."+varName, nil)
+}
+
+// Warns about environment overrides that apply to more than one target.  This
+// happens when target names differ only in non-alphanumeric characters, e.g.,
+// "targets/my-blinky" and "targets/my_blinky".
+func warnAmbiguousEnvOverrides(targets map[string]*Target) {
+	// [env-prefix] => [target-name]
+	prefixMap := map[string][]string{}
+	for name, t := range targets {
+		prefix := envOverridePrefix(t.basePkg.Name())
+		prefixMap[prefix] = append(prefixMap[prefix], name)
+	}
+
+	for prefix, names := range prefixMap {
+		if len(names) < 2 {
+			continue
+		}
+		sort.Strings(names)
+
+		for _, v := range envOverrideVars {
+			envName := prefix + strings.ToUpper(v)
+			if _, ok := os.LookupEnv(envName); ok {
+				util.OneTimeWarning(
+					"environment variable %s overrides multiple targets: %s",
+					envName, strings.Join(names, ", "))
+			}
+		}
+	}
+}
+
 func (target *Target) Load(basePkg *pkg.LocalPackage) error {
 	yc, err := config.ReadFile(target.TargetYamlPath())
 	if err != nil {
@@ -91,17 +178,69 @@ func (target *Target) Load(basePkg *pkg.LocalPackage) error {
 	}
 
 	target.TargetY = yc
+	target.loadInlineSyscfg()
+	target.LoadVars()
+
+	target.locked, err = basePkg.PkgY.GetValBoolDflt("pkg.locked", nil, false)
+	util.OneTimeWarningError(err)
+
+	// Note: App not required in the case of unit tests.
+
+	// Remember the name of the configuration file so that it can be specified
+	// as a dependency to the compiler.
+	target.basePkg.AddCfgFilename(target.TargetYamlPath())
+
+	return nil
+}
+
+// Moves syscfg settings stored in target.yml into the base package's syscfg
+// configuration, where the rest of newt expects them.  Settings in target.yml
+// take precedence over those in syscfg.yml.
+func (target *Target) loadInlineSyscfg() {
+	if target.TargetY.Tree()["syscfg"] == nil {
+		return
+	}
+	target.inlineSyscfg = true
+
+	vals, err := target.TargetY.GetValStringMapString("syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+	target.TargetY.Delete("syscfg")
+
+	if len(vals) == 0 {
+		return
+	}
 
-	target.BspName, err = yc.GetValString("target.bsp", nil)
+	sysVals, err := target.basePkg.SyscfgY.GetValStringMapString(
+		"syscfg.vals", nil)
 	util.OneTimeWarningError(err)
+	if sysVals == nil {
+		sysVals = map[string]string{}
+	}
+	for k, v := range vals {
+		sysVals[k] = v
+	}
+
+	target.basePkg.SyscfgY.Replace("syscfg.vals",
+		util.StringMapStringToItfMapItf(sysVals))
+}
 
-	target.AppName, err = yc.GetValString("target.app", nil)
+// LoadVars populates the target's variable fields (BspName, AppName, etc.)
+// from its target.yml configuration.  This must be called after TargetY is
+// modified for the fields and accessors to reflect the change.
+func (target *Target) LoadVars() {
+	yc := target.TargetY
+
+	var err error
+	target.BspName, err = target.readVar(yc, "bsp")
+	util.OneTimeWarningError(err)
+
+	target.AppName, err = target.readVar(yc, "app")
 	util.OneTimeWarningError(err)
 
-	target.LoaderName, err = yc.GetValString("target.loader", nil)
+	target.LoaderName, err = target.readVar(yc, "loader")
 	util.OneTimeWarningError(err)
 
-	target.BuildProfile, err = yc.GetValString("target.build_profile", nil)
+	target.BuildProfile, err = target.readVar(yc, "build_profile")
 	util.OneTimeWarningError(err)
 
 	if target.BuildProfile == "" {
@@ -110,7 +249,7 @@ func (target *Target) Load(basePkg *pkg.LocalPackage) error {
 
 	target.HeaderSize = DEFAULT_HEADER_SIZE
 
-	hsStr, err := yc.GetValString("target.header_size", nil)
+	hsStr, err := target.readVar(yc, "header_size")
 	util.OneTimeWarningError(err)
 	if hsStr != "" {
 		hs, err := strconv.ParseUint(hsStr, 0, 32)
@@ -119,7 +258,7 @@ func (target *Target) Load(basePkg *pkg.LocalPackage) error {
 		}
 	}
 
-	target.KeyFile, err = yc.GetValString("target.key_file", nil)
+	target.KeyFile, err = target.readVar(yc, "key_file")
 	util.OneTimeWarningError(err)
 
 	if target.KeyFile != "" {
@@ -133,14 +272,6 @@ func (target *Target) Load(basePkg *pkg.LocalPackage) error {
 	target.PkgProfiles, err = yc.GetValStringMapString(
 		"target.package_profiles", nil)
 	util.OneTimeWarningError(err)
-
-	// Note: App not required in the case of unit tests.
-
-	// Remember the name of the configuration file so that it can be specified
-	// as a dependency to the compiler.
-	target.basePkg.AddCfgFilename(target.TargetYamlPath())
-
-	return nil
 }
 
 func (target *Target) Validate(appRequired bool) error {
@@ -212,10 +343,26 @@ func (target *Target) ShortName() string {
 	return filepath.Base(target.Name())
 }
 
+// Locked indicates whether the target is protected against modification.
+func (target *Target) Locked() bool {
+	return target.locked
+}
+
+// SetLocked locks or unlocks the target.  The change is persisted the next
+// time the target's package is saved.
+func (target *Target) SetLocked(locked bool) {
+	target.locked = locked
+	target.basePkg.PkgY.Replace("pkg.locked", locked)
+}
+
 func (target *Target) Clone(newRepo *repo.Repo, newName string) *Target {
-	// Clone the target.
+	// Clone the target.  The clone's configuration is independent of the
+	// original's.  A lock protects a specific target, so the clone is never
+	// locked.
 	newTarget := *target
 	newTarget.basePkg = target.basePkg.Clone(newRepo, newName)
+	newTarget.TargetY = target.TargetY.Clone()
+	newTarget.SetLocked(false)
 
 	// Insert the clone into the global target map.
 	GetTargets()[newTarget.FullName()] = &newTarget
@@ -270,6 +417,22 @@ func (target *Target) Bsp() *pkg.LocalPackage {
 	return target.ResolvePackageName(target.BspName)
 }
 
+// PkgRef returns the package referred to by one of the target's package
+// variables ("app", "bsp", or "loader").  Nil is returned if the variable is
+// unset, does not name a package, or names a package that does not exist.
+func (target *Target) PkgRef(varName string) *pkg.LocalPackage {
+	switch strings.TrimPrefix(varName, "target.") {
+	case "app":
+		return target.App()
+	case "bsp":
+		return target.Bsp()
+	case "loader":
+		return target.Loader()
+	default:
+		return nil
+	}
+}
+
 // Methods below resolve package by name as stated in YML file (so do not follow links)
 // e.g. to use as seed for dependencies calculation
 
@@ -285,26 +448,225 @@ func (target *Target) BspYml() *pkg.LocalPackage {
 	return target.ResolvePackageYmlName(target.BspName)
 }
 
+func pkgVarSliceString(pack *pkg.LocalPackage, key string) string {
+	vals, err := pack.PkgY.GetValStringSlice(key, nil)
+	util.OneTimeWarningError(err)
+
+	sort.Strings(vals)
+	var buffer bytes.Buffer
+	for _, v := range vals {
+		buffer.WriteString(v)
+		buffer.WriteString(" ")
+	}
+	return buffer.String()
+}
+
+// ToMap returns the target's configuration variables as a map of
+// variable-name to string-value.  The "target." prefix is stripped from
+// settings read from target.yml.  A few variables (syscfg and the build
+// flags) come from the base package rather than the target.  Variables
+// without a value map to an empty string.
+func (t *Target) ToMap() map[string]string {
+	kvPairs := map[string]string{}
+
+	settings := t.TargetY.AllSettingsAsStrings()
+	for k, v := range settings {
+		kvPairs[strings.TrimPrefix(k, "target.")] = v
+	}
+
+	scfg, err := t.basePkg.SyscfgY.GetValStringMapString("syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+	kvPairs["syscfg"] = syscfg.KeyValueToStr(scfg)
+
+	kvPairs["cflags"] = pkgVarSliceString(t.basePkg, "pkg.cflags")
+	kvPairs["cxxflags"] = pkgVarSliceString(t.basePkg, "pkg.cxxflags")
+	kvPairs["lflags"] = pkgVarSliceString(t.basePkg, "pkg.lflags")
+	kvPairs["aflags"] = pkgVarSliceString(t.basePkg, "pkg.aflags")
+
+	return kvPairs
+}
+
+// TargetDiff describes a configuration variable whose value differs between
+// two targets.  Syscfg settings are reported individually with a "syscfg."
+// prefix (e.g., "syscfg.LOG_LEVEL").  An unset variable has a value of "".
+type TargetDiff struct {
+	Name       string
+	Value      string
+	OtherValue string
+}
+
+// FlatMap returns the target's configuration in the form used for
+// comparisons.  Unlike ToMap(), syscfg settings are split into separate
+// "syscfg.<name>" entries and build flags retain their original order.
+func (t *Target) FlatMap() map[string]string {
+	m := t.ToMap()
+	delete(m, "syscfg")
+
+	scfg, err := t.basePkg.SyscfgY.GetValStringMapString("syscfg.vals", nil)
+	util.OneTimeWarningError(err)
+	for k, v := range scfg {
+		m["syscfg."+k] = v
+	}
+
+	for _, k := range []string{"aflags", "cflags", "cxxflags", "lflags"} {
+		vals, err := t.basePkg.PkgY.GetValStringSlice("pkg."+k, nil)
+		util.OneTimeWarningError(err)
+		m[k] = strings.Join(vals, " ")
+	}
+
+	return m
+}
+
+// Compare reports the differences between this target's configuration and
+// another's.  The target variables, syscfg settings, and build flags are
+// compared; the targets' names are not.  The returned slice is sorted by
+// variable name and is empty if the configurations are identical.
+func (t *Target) Compare(other *Target) []TargetDiff {
+	m := t.FlatMap()
+	om := other.FlatMap()
+
+	names := []string{}
+	for k, _ := range m {
+		names = append(names, k)
+	}
+	for k, _ := range om {
+		if _, ok := m[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	diffs := []TargetDiff{}
+	for _, name := range names {
+		if m[name] != om[name] {
+			diffs = append(diffs, TargetDiff{
+				Name:       name,
+				Value:      m[name],
+				OtherValue: om[name],
+			})
+		}
+	}
+
+	return diffs
+}
+
+// ConfigHash returns a hex-encoded SHA-256 digest of the target's
+// configuration: its target variables, syscfg settings, and build flags.  The
+// digest is independent of key order and formatting, so targets with
+// identical configurations have the same hash.  The target's name is not
+// included.
+func (t *Target) ConfigHash() string {
+	m := t.FlatMap()
+
+	names := []string{}
+	for k, v := range m {
+		// An empty value is equivalent to an unset one.
+		if v != "" {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	for _, name := range names {
+		val := m[name]
+		switch name {
+		case "aflags", "cflags", "cxxflags", "lflags":
+			// Flag order is significant, but spacing is not.
+			val = strings.Join(strings.Fields(val), " ")
+		}
+
+		// Prefix each string with its length so that entries can't run
+		// together ambiguously.
+		fmt.Fprintf(h, "%d:%s%d:%s", len(name), name, len(val), val)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Equal indicates whether two targets have identical configurations.  The
+// targets' names are not considered.
+func (t *Target) Equal(other *Target) bool {
+	return len(t.Compare(other)) == 0
+}
+
 // Save the target's configuration elements
 func (t *Target) Save() error {
 	if err := t.basePkg.Save(); err != nil {
 		return err
 	}
 
+	if !t.syscfgInline() {
+		if err := t.saveTargetYaml(); err != nil {
+			return err
+		}
+	}
+
+	return t.SaveSyscfg()
+}
+
+// Indicates whether the target's syscfg settings are saved in target.yml.
+// This is the case if target.yml already contains them or if the project sets
+// `project.target_inline_syscfg`.
+func (t *Target) syscfgInline() bool {
+	return t.inlineSyscfg || project.GetProject().TargetInlineSyscfg()
+}
+
+// Writes the target.yml file.  If the target's syscfg settings are stored
+// inline, they are included.
+func (t *Target) saveTargetYaml() error {
+	yc := t.TargetY
+	if t.syscfgInline() {
+		yc = t.TargetY.Clone()
+
+		vals, err := t.basePkg.SyscfgY.GetValStringMapString(
+			"syscfg.vals", nil)
+		util.OneTimeWarningError(err)
+		if len(vals) > 0 {
+			yc.Replace("syscfg.vals", util.StringMapStringToItfMapItf(vals))
+		}
+	}
+
 	file, err := os.Create(t.TargetYamlPath())
 	if err != nil {
 		return util.NewNewtError(err.Error())
 	}
 	defer file.Close()
 
-	s := t.TargetY.YAML()
-	file.WriteString(s)
+	file.WriteString(yc.YAML())
+
+	return nil
+}
+
+// SaveSyscfg saves the target's syscfg settings.  Normally, these are written
+// to the base package's syscfg.yml file.  If the target stores its settings
+// inline, target.yml is written instead, and syscfg.yml only retains the
+// remaining syscfg configuration (it is removed if nothing remains).
+func (t *Target) SaveSyscfg() error {
+	if !t.syscfgInline() {
+		return t.basePkg.SaveSyscfg()
+	}
 
-	if err := t.basePkg.SaveSyscfg(); err != nil {
+	if err := t.saveTargetYaml(); err != nil {
 		return err
 	}
 
-	return nil
+	rest := t.basePkg.SyscfgY.Clone()
+	rest.Replace("syscfg.vals", nil)
+	if len(rest.AllSettings()) == 0 {
+		err := os.Remove(t.basePkg.SyscfgYamlPath())
+		if err != nil && !os.IsNotExist(err) {
+			return util.ChildNewtError(err)
+		}
+		return nil
+	}
+
+	orig := t.basePkg.SyscfgY
+	t.basePkg.SyscfgY = rest
+	err := t.basePkg.SaveSyscfg()
+	t.basePkg.SyscfgY = orig
+
+	return err
 }
 
 func buildTargetMap() error {
@@ -324,6 +686,8 @@ func buildTargetMap() error {
 		}
 	}
 
+	warnAmbiguousEnvOverrides(globalTargetMap)
+
 	return nil
 }
 
@@ -0,0 +1,208 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package target_test
+
+import (
+	"os"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/pkg"
+	"mynewt.apache.org/newt/newt/target"
+	"mynewt.apache.org/newt/newt/testutil"
+)
+
+func pkgName(lpkg *pkg.LocalPackage) string {
+	if lpkg == nil {
+		return ""
+	}
+	return lpkg.FullName()
+}
+
+func TestTargetAccessors(t *testing.T) {
+	_, cleanup := testutil.NewProject(t, map[string]string{
+		"targets/bar/pkg.yml": "pkg.name: targets/bar\npkg.type: target\n",
+		"targets/bar/target.yml": "target.app: apps/nope\n" +
+			"target.bsp: hw/bsp/mybsp\n" +
+			"target.loader: apps/blinky\n",
+	})
+	defer cleanup()
+
+	tests := []struct {
+		target  string
+		app     string
+		bsp     string
+		loader  string
+		profile string
+	}{
+		{
+			target:  "targets/foo",
+			app:     "apps/blinky",
+			bsp:     "hw/bsp/mybsp",
+			loader:  "",
+			profile: "debug",
+		},
+		{
+			// A nonexistent app doesn't resolve; an unspecified profile
+			// is the default.
+			target:  "targets/bar",
+			app:     "",
+			bsp:     "hw/bsp/mybsp",
+			loader:  "apps/blinky",
+			profile: target.DEFAULT_BUILD_PROFILE,
+		},
+	}
+
+	for _, test := range tests {
+		tgt := target.GetTargets()[test.target]
+		if tgt == nil {
+			t.Fatalf("target %s not found", test.target)
+		}
+
+		if got := pkgName(tgt.App()); got != test.app {
+			t.Errorf("%s: App() = %q; want %q", test.target, got, test.app)
+		}
+		if got := pkgName(tgt.Bsp()); got != test.bsp {
+			t.Errorf("%s: Bsp() = %q; want %q", test.target, got, test.bsp)
+		}
+		if got := pkgName(tgt.Loader()); got != test.loader {
+			t.Errorf("%s: Loader() = %q; want %q",
+				test.target, got, test.loader)
+		}
+		if tgt.BuildProfile != test.profile {
+			t.Errorf("%s: BuildProfile = %q; want %q",
+				test.target, tgt.BuildProfile, test.profile)
+		}
+
+		refs := map[string]string{
+			"app":           test.app,
+			"target.bsp":    test.bsp,
+			"loader":        test.loader,
+			"build_profile": "",
+		}
+		for name, want := range refs {
+			if got := pkgName(tgt.PkgRef(name)); got != want {
+				t.Errorf("%s: PkgRef(%s) = %q; want %q",
+					test.target, name, got, want)
+			}
+		}
+	}
+}
+
+func TestTargetLoadVars(t *testing.T) {
+	_, cleanup := testutil.NewProject(t, nil)
+	defer cleanup()
+
+	tgt := target.GetTargets()["targets/foo"]
+	tgt.TargetY.Replace("target.app", "libs/a")
+	tgt.TargetY.Replace("target.build_profile", "optimized")
+
+	// The fields are stale until the variables are reloaded.
+	if tgt.AppName != "apps/blinky" {
+		t.Errorf("AppName = %q before reload; want apps/blinky", tgt.AppName)
+	}
+
+	tgt.LoadVars()
+
+	if got := pkgName(tgt.App()); got != "libs/a" {
+		t.Errorf("App() = %q; want libs/a", got)
+	}
+	if tgt.BuildProfile != "optimized" {
+		t.Errorf("BuildProfile = %q; want optimized", tgt.BuildProfile)
+	}
+
+	// Removing the profile reverts to the default.
+	tgt.TargetY.Replace("target.build_profile", "")
+	tgt.LoadVars()
+	if tgt.BuildProfile != target.DEFAULT_BUILD_PROFILE {
+		t.Errorf("BuildProfile = %q after clearing; want %q",
+			tgt.BuildProfile, target.DEFAULT_BUILD_PROFILE)
+	}
+}
+
+func TestTargetEnvOverride(t *testing.T) {
+	_, cleanup := testutil.NewProject(t, map[string]string{
+		"targets/a/blinky/pkg.yml": "pkg.name: targets/a/blinky\n" +
+			"pkg.type: target\n",
+		"targets/a/blinky/target.yml": "target.app: apps/blinky\n" +
+			"target.bsp: hw/bsp/mybsp\n",
+		"targets/b/blinky/pkg.yml": "pkg.name: targets/b/blinky\n" +
+			"pkg.type: target\n",
+		"targets/b/blinky/target.yml": "target.app: apps/blinky\n" +
+			"target.bsp: hw/bsp/mybsp\n",
+	})
+	defer cleanup()
+
+	envs := map[string]string{
+		"NEWT_TARGET_TARGETS_FOO_BUILD_PROFILE":      "optimized",
+		"NEWT_TARGET_TARGETS_A_BLINKY_BUILD_PROFILE": "speed",
+		"NEWT_TARGET_TARGETS_A_BLINKY_APP":           "libs/a",
+	}
+	for name, val := range envs {
+		os.Setenv(name, val)
+		defer os.Unsetenv(name)
+	}
+
+	tests := []struct {
+		target  string
+		app     string
+		profile string
+	}{
+		{
+			// The override takes precedence over target.yml.
+			target:  "targets/foo",
+			app:     "apps/blinky",
+			profile: "optimized",
+		},
+		{
+			target:  "targets/a/blinky",
+			app:     "libs/a",
+			profile: "speed",
+		},
+		{
+			// Same base name as targets/a/blinky; not overridden.
+			target:  "targets/b/blinky",
+			app:     "apps/blinky",
+			profile: target.DEFAULT_BUILD_PROFILE,
+		},
+	}
+
+	targets := target.GetTargets()
+	for _, test := range tests {
+		tgt := targets[test.target]
+		if tgt == nil {
+			t.Fatalf("target %s not found", test.target)
+		}
+
+		if tgt.AppName != test.app {
+			t.Errorf("%s: AppName = %q; want %q",
+				test.target, tgt.AppName, test.app)
+		}
+		if tgt.BuildProfile != test.profile {
+			t.Errorf("%s: BuildProfile = %q; want %q",
+				test.target, tgt.BuildProfile, test.profile)
+		}
+	}
+
+	// Overrides are never saved.
+	yc := targets["targets/foo"].TargetY
+	if got, _ := yc.GetValString("target.build_profile", nil); got != "debug" {
+		t.Errorf("target.yml build_profile = %q; want debug", got)
+	}
+}
@@ -0,0 +1,179 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+// Package testutil creates small on-disk newt projects for use in tests.
+package testutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mynewt.apache.org/newt/newt/project"
+	"mynewt.apache.org/newt/newt/target"
+)
+
+// BaseFiles contains a minimal buildable project: an app that depends on two
+// libraries, a BSP, a compiler, and a target ("targets/foo") that ties them
+// together.  Library "libs/a" defines a few syscfg settings and an init
+// function (sysinit requires at least one).  The project is named after the
+// core repo because target resolution requires that repo to exist.
+var BaseFiles = map[string]string{
+	"project.yml": `project.name: "apache-mynewt-core"
+`,
+	"apps/blinky/pkg.yml": `pkg.name: apps/blinky
+pkg.type: app
+pkg.deps:
+    - libs/a
+    - libs/b
+`,
+	"libs/a/pkg.yml": `pkg.name: libs/a
+pkg.type: lib
+pkg.deps:
+    - libs/b
+pkg.init:
+    a_init: 100
+`,
+	"libs/a/syscfg.yml": `syscfg.defs:
+    A_VAL:
+        description: val
+        value: 1
+    A_BOOL:
+        description: bool
+        value: 0
+`,
+	"libs/b/pkg.yml": `pkg.name: libs/b
+pkg.type: lib
+`,
+	"hw/bsp/mybsp/pkg.yml": `pkg.name: hw/bsp/mybsp
+pkg.type: bsp
+`,
+	"hw/bsp/mybsp/bsp.yml": `bsp.arch: sim
+bsp.compiler: compiler/sim
+bsp.linkerscript: []
+bsp.flash_map:
+    areas:
+        FLASH_AREA_BOOTLOADER:
+            device: 0
+            offset: 0x00000000
+            size: 16kB
+        FLASH_AREA_IMAGE_0:
+            device: 0
+            offset: 0x00008000
+            size: 16kB
+        FLASH_AREA_IMAGE_1:
+            device: 0
+            offset: 0x00010000
+            size: 16kB
+        FLASH_AREA_IMAGE_SCRATCH:
+            device: 0
+            offset: 0x00018000
+            size: 16kB
+`,
+	"compiler/sim/pkg.yml": `pkg.name: compiler/sim
+pkg.type: compiler
+`,
+	"compiler/sim/compiler.yml": `compiler.path.cc: gcc
+compiler.path.as: gcc
+compiler.path.archive: ar
+compiler.path.objdump: objdump
+compiler.path.objsize: size
+compiler.path.objcopy: objcopy
+`,
+	"targets/foo/pkg.yml": `pkg.name: targets/foo
+pkg.type: target
+`,
+	"targets/foo/target.yml": `target.app: apps/blinky
+target.bsp: hw/bsp/mybsp
+target.build_profile: debug
+`,
+	"targets/foo/syscfg.yml": `syscfg.vals:
+    A_VAL: 3
+`,
+}
+
+// WriteFiles writes the specified files beneath `dir`.  Keys are
+// slash-separated paths relative to `dir`; values are file contents.
+func WriteFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// NewProject creates a project in a temporary directory from BaseFiles plus
+// the specified extra files, makes it the current project, and changes to its
+// directory.  An extra file with an empty value removes the corresponding base
+// file.  The returned function restores the previous working directory,
+// resets newt's global project state, and deletes the project.
+func NewProject(t *testing.T, extra map[string]string) (
+	*project.Project, func()) {
+
+	dir, err := ioutil.TempDir("", "newt-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for name, contents := range BaseFiles {
+		files[name] = contents
+	}
+	for name, contents := range extra {
+		if contents == "" {
+			delete(files, name)
+		} else {
+			files[name] = contents
+		}
+	}
+	WriteFiles(t, dir, files)
+
+	oldWd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cleanup := func() {
+		os.Chdir(oldWd)
+		target.ResetTargets()
+		project.ResetProject()
+		os.RemoveAll(dir)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	target.ResetTargets()
+	project.ResetProject()
+
+	proj, err := project.TryGetProject()
+	if err != nil {
+		cleanup()
+		t.Fatal(err)
+	}
+
+	return proj, cleanup
+}
@@ -244,6 +244,65 @@ func NewYCfg(name string) YCfg {
 	}
 }
 
+// Makes a deep copy of a value read from a YAML file.  Only sequences and
+// maps need to be copied; other values are immutable.
+func cloneValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, elem := range v {
+			c[i] = cloneValue(elem)
+		}
+		return c
+
+	case []string:
+		return append([]string{}, v...)
+
+	case map[interface{}]interface{}:
+		c := make(map[interface{}]interface{}, len(v))
+		for k, elem := range v {
+			c[k] = cloneValue(elem)
+		}
+		return c
+
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, elem := range v {
+			c[k] = cloneValue(elem)
+		}
+		return c
+
+	default:
+		return v
+	}
+}
+
+func cloneNode(node *YCfgNode, parent *YCfgNode) *YCfgNode {
+	c := *node
+	c.Parent = parent
+	c.Value = cloneValue(node.Value)
+	c.Children = make(YCfgTree, len(node.Children))
+	for name, child := range node.Children {
+		c.Children[name] = cloneNode(child, &c)
+	}
+
+	return &c
+}
+
+// Clone returns a deep copy of the YCfg.  Modifying the copy does not affect
+// the original.
+func (yc *YCfg) Clone() YCfg {
+	c := YCfg{
+		name: yc.name,
+		tree: make(YCfgTree, len(yc.tree)),
+	}
+	for name, node := range yc.tree {
+		c.tree[name] = cloneNode(node, nil)
+	}
+
+	return c
+}
+
 func (yc *YCfg) find(key string) *YCfgNode {
 	elems := strings.Split(key, ".")
 	if len(elems) == 0 {
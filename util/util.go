@@ -598,6 +598,57 @@ func MoveDir(srcDir string, destDir string) error {
 	return nil
 }
 
+// Number of times RemoveAllRetry attempts a removal, and the delay before the
+// first retry.  The delay doubles after each failed attempt.
+const removeAllAttempts = 5
+const removeAllInitialDelay = 100 * time.Millisecond
+
+// Clears the read-only attribute of every file and directory under the
+// specified path.  On Windows, os.RemoveAll fails on read-only files.  Errors
+// are ignored; the subsequent removal attempt reports any real problem.
+func clearReadOnly(path string) {
+	filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().Perm()&0200 == 0 {
+			os.Chmod(p, info.Mode().Perm()|0200)
+		}
+		return nil
+	})
+}
+
+// RemoveAllRetry removes the specified path and everything it contains, like
+// os.RemoveAll.  On Windows, removal intermittently fails when another process
+// (e.g., an editor or virus scanner) briefly holds a file open, so failed
+// attempts are retried with a backoff after clearing read-only attributes.  An
+// error is returned only after all attempts fail.
+func RemoveAllRetry(path string) error {
+	delay := removeAllInitialDelay
+
+	var err error
+	for i := 0; i < removeAllAttempts; i++ {
+		if i > 0 {
+			log.Debugf("Failed to remove %s (%s); retrying in %s",
+				path, err.Error(), delay)
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		if runtime.GOOS == "windows" && i > 0 {
+			clearReadOnly(path)
+		}
+
+		err = os.RemoveAll(path)
+		if err == nil {
+			return nil
+		}
+
+		if runtime.GOOS != "windows" {
+			break
+		}
+	}
+
+	return FmtNewtError("Failed to remove %s: %s", path, err.Error())
+}
+
 func CallInDir(path string, execFunc func() error) error {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -46,6 +46,10 @@ type YamlDispatchFn func(*yaml_parser_t, *yaml_event_t,
 var decodeFilename string
 var decodeDispatch map[yaml_event_type_t]YamlDispatchFn
 
+// Values of the anchors (&name) defined so far in the current stream.  Key is
+// the anchor name.
+var decodeAnchors map[string]decodeCtxt
+
 // Fills in the decodeDispatch table.  This function is necessary because
 // statically initializing the table triggers a spurious "initialization loop"
 // error.
@@ -58,7 +62,7 @@ func initDecodeDispatch() {
 		yaml_STREAM_START_EVENT:   decodeNoOp,
 		yaml_DOCUMENT_START_EVENT: decodeNoOp,
 		yaml_DOCUMENT_END_EVENT:   decodeNoOp,
-		yaml_ALIAS_EVENT:          decodeNoOp,
+		yaml_ALIAS_EVENT:          decodeAlias,
 		yaml_STREAM_END_EVENT:     decodeStreamEnd,
 		yaml_SCALAR_EVENT:         decodeScalar,
 		yaml_SEQUENCE_START_EVENT: decodeSequenceStart,
@@ -201,16 +205,51 @@ func decodeMappingKey(parser *yaml_parser_t,
 	}
 }
 
+// Converts the value of a merge key (`<<`) to the list of mappings it merges.
+// The value must be a mapping or a sequence of mappings.
+func mergeMappings(parser *yaml_parser_t,
+	value interface{}) ([]map[interface{}]interface{}, error) {
+
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		return []map[interface{}]interface{}{v}, nil
+
+	case []interface{}:
+		maps := make([]map[interface{}]interface{}, len(v))
+		for i, elem := range v {
+			m, ok := elem.(map[interface{}]interface{})
+			if !ok {
+				return nil, decodeError(parser,
+					"merge key sequence must contain only mappings")
+			}
+			maps[i] = m
+		}
+		return maps, nil
+
+	default:
+		return nil, decodeError(parser,
+			"merge key value must be a mapping or a sequence of mappings")
+	}
+}
+
 func decodeMappingStart(parser *yaml_parser_t, event *yaml_event_t,
 	parentCtxt *decodeCtxt) (decodeCtxt, error) {
 
 	ctxt := decodeCtxt{state: CTXT_STATE_MAPPING}
 
+	// Mappings merged in with the `<<` key.  These are applied after the
+	// mapping has been decoded because explicit keys take precedence
+	// regardless of where the merge key appears.
+	var merges []map[interface{}]interface{}
+
 	for {
 		subCtxt, err := decodeMappingKey(parser, &ctxt)
-		if err != nil || subCtxt.state == CTXT_STATE_DONE {
+		if err != nil {
 			return ctxt, err
 		}
+		if subCtxt.state == CTXT_STATE_DONE {
+			break
+		}
 		key := stringValue(subCtxt.value)
 
 		subCtxt, err = decodeNextValue(parser, &ctxt)
@@ -220,9 +259,32 @@ func decodeMappingStart(parser *yaml_parser_t, event *yaml_event_t,
 		if subCtxt.state == CTXT_STATE_DONE {
 			return ctxt, decodeError(parser, "mapping lacks value")
 		}
+
+		if key == "<<" {
+			maps, err := mergeMappings(parser, subCtxt.value)
+			if err != nil {
+				return ctxt, err
+			}
+			merges = append(merges, maps...)
+			continue
+		}
+
 		mappingAdd(&ctxt, key, subCtxt.value)
 	}
 
+	// Earlier mappings in a merge sequence take precedence over later ones.
+	for _, m := range merges {
+		for k, v := range m {
+			if ctxt.value != nil {
+				existing := ctxt.value.(map[interface{}]interface{})
+				if _, ok := existing[k]; ok {
+					continue
+				}
+			}
+			mappingAdd(&ctxt, stringValue(k), copyValue(v))
+		}
+	}
+
 	return ctxt, nil
 }
 
@@ -237,6 +299,47 @@ func decodeMappingEnd(parser *yaml_parser_t, event *yaml_event_t,
 	return ctxt, nil
 }
 
+// Makes a deep copy of a decoded value.  Aliased values are copied so that
+// modifying one reference does not affect the others.
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, elem := range v {
+			c[i] = copyValue(elem)
+		}
+		return c
+
+	case map[interface{}]interface{}:
+		c := make(map[interface{}]interface{}, len(v))
+		for k, elem := range v {
+			c[k] = copyValue(elem)
+		}
+		return c
+
+	default:
+		return v
+	}
+}
+
+// Decodes an alias (*name) by substituting the value of the corresponding
+// anchor.
+func decodeAlias(parser *yaml_parser_t, event *yaml_event_t,
+	parentCtxt *decodeCtxt) (decodeCtxt, error) {
+
+	name := string(event.anchor)
+	anchored, ok := decodeAnchors[name]
+	if !ok {
+		return decodeCtxt{state: CTXT_STATE_NONE},
+			decodeError(parser, "unknown alias: %s", name)
+	}
+
+	return decodeCtxt{
+		state: anchored.state,
+		value: copyValue(anchored.value),
+	}, nil
+}
+
 func decodeScalar(parser *yaml_parser_t, event *yaml_event_t,
 	parentCtxt *decodeCtxt) (decodeCtxt, error) {
 
@@ -267,13 +370,23 @@ func decodeEvent(parser *yaml_parser_t,
 	}
 
 	ctxt, err := fn(parser, &event, parentCtxt)
-	return ctxt, err
+	if err != nil {
+		return ctxt, err
+	}
+
+	// Remember anchored values so that later aliases can refer to them.
+	if len(event.anchor) > 0 && event.typ != yaml_ALIAS_EVENT {
+		decodeAnchors[string(event.anchor)] = ctxt
+	}
+
+	return ctxt, nil
 }
 
 func DecodeStream(b []byte, values map[string]interface{}) error {
 	parser := yaml_parser_t{}
 
 	initDecodeDispatch()
+	decodeAnchors = map[string]decodeCtxt{}
 
 	yaml_parser_initialize(&parser)
 	yaml_parser_set_input_string(&parser, b)
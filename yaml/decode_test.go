@@ -0,0 +1,155 @@
+/**
+ * Licensed to the Apache Software Foundation (ASF) under one
+ * or more contributor license agreements.  See the NOTICE file
+ * distributed with this work for additional information
+ * regarding copyright ownership.  The ASF licenses this file
+ * to you under the Apache License, Version 2.0 (the
+ * "License"); you may not use this file except in compliance
+ * with the License.  You may obtain a copy of the License at
+ *
+ *  http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing,
+ * software distributed under the License is distributed on an
+ * "AS IS" BASIS, WITHOUT WARRANTIES OR CONDITIONS OF ANY
+ * KIND, either express or implied.  See the License for the
+ * specific language governing permissions and limitations
+ * under the License.
+ */
+
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+type m = map[interface{}]interface{}
+
+func TestDecodeAnchors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]interface{}
+	}{
+		{
+			name: "anchored cflags block",
+			src: `common_cflags: &cflags
+    - -DFOO
+    - -Wall
+pkg.cflags: *cflags
+pkg.cxxflags: *cflags
+`,
+			want: map[string]interface{}{
+				"common_cflags": []interface{}{"-DFOO", "-Wall"},
+				"pkg.cflags":    []interface{}{"-DFOO", "-Wall"},
+				"pkg.cxxflags":  []interface{}{"-DFOO", "-Wall"},
+			},
+		},
+		{
+			name: "anchored scalar",
+			src: `a: &val 10
+b: *val
+`,
+			want: map[string]interface{}{
+				"a": 10,
+				"b": 10,
+			},
+		},
+		{
+			name: "merge key",
+			src: `base: &base
+    description: shared
+    value: 0
+syscfg.defs:
+    B_X:
+        <<: *base
+        value: 1
+    B_Y:
+        <<: *base
+`,
+			want: map[string]interface{}{
+				"base": m{"description": "shared", "value": 0},
+				"syscfg.defs": m{
+					"B_X": m{"description": "shared", "value": 1},
+					"B_Y": m{"description": "shared", "value": 0},
+				},
+			},
+		},
+		{
+			name: "merge key sequence",
+			src: `one: &one
+    a: 1
+    b: 1
+two: &two
+    b: 2
+    c: 2
+merged:
+    <<: [*one, *two]
+    c: 3
+`,
+			want: map[string]interface{}{
+				"one":    m{"a": 1, "b": 1},
+				"two":    m{"b": 2, "c": 2},
+				"merged": m{"a": 1, "b": 1, "c": 3},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got := map[string]interface{}{}
+		if err := Unmarshal([]byte(test.src), got); err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err.Error())
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v; want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// Verifies that modifying an aliased value does not modify the anchored
+// value.
+func TestDecodeAliasCopy(t *testing.T) {
+	src := `a: &flags
+    - -DFOO
+b: *flags
+`
+	got := map[string]interface{}{}
+	if err := Unmarshal([]byte(src), got); err != nil {
+		t.Fatal(err)
+	}
+
+	got["b"].([]interface{})[0] = "-DBAR"
+	if a := got["a"].([]interface{})[0]; a != "-DFOO" {
+		t.Errorf("anchored value changed to %v", a)
+	}
+}
+
+func TestDecodeAnchorErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{
+			name: "unknown alias",
+			src:  "a: *nope\n",
+		},
+		{
+			name: "scalar merge",
+			src:  "base: &base 1\nb:\n    <<: *base\n",
+		},
+		{
+			name: "sequence of scalars merge",
+			src:  "b:\n    <<: [1, 2]\n",
+		},
+	}
+
+	for _, test := range tests {
+		got := map[string]interface{}{}
+		if err := Unmarshal([]byte(test.src), got); err == nil {
+			t.Errorf("%s: expected error; got %v", test.name, got)
+		}
+	}
+}
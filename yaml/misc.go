@@ -28,12 +28,50 @@ import (
 func EscapeString(s string) string {
 	special := ":{}[],&*#?|-<>=!%@\\\"'"
 	if strings.ContainsAny(s, special) {
-		return "\"" + strings.Replace(s, "\"", "\\\"", -1) + "\""
+		s = strings.Replace(s, "\\", "\\\\", -1)
+		s = strings.Replace(s, "\"", "\\\"", -1)
+		return "\"" + s + "\""
 	} else {
 		return s
 	}
 }
 
+// Converts a scalar value to its YAML representation.
+func scalarToYaml(val interface{}) string {
+	return EscapeString(fmt.Sprintf("%v", val))
+}
+
+// Converts a sequence element to a YAML string.  Scalars are written inline
+// following the "- " marker; collections are written on subsequent lines.
+func seqElemToYaml(elem interface{}, indent int) string {
+	switch elem.(type) {
+	case []interface{}, []string, map[interface{}]interface{},
+		map[string]interface{}, map[string]string:
+
+		return fmt.Sprintf("%*s-%s", indent, "", KvToYaml("", elem, indent))
+
+	default:
+		return fmt.Sprintf("%*s- %s\n", indent, "", scalarToYaml(elem))
+	}
+}
+
+// Converts a map to a YAML string.  Keys are sorted by their string
+// representation so that the output does not depend on map iteration order.
+func mapToYaml(m map[string]interface{}, indent int) string {
+	keys := make([]string, 0, len(m))
+	for k, _ := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	s := ""
+	for _, k := range keys {
+		s += KvToYaml(k, m[k], indent)
+	}
+
+	return s
+}
+
 // Converts a key-value pair to a YAML string.
 func KvToYaml(key string, val interface{}, indent int) string {
 	s := ""
@@ -46,41 +84,39 @@ func KvToYaml(key string, val interface{}, indent int) string {
 	case []interface{}:
 		s += "\n"
 		for _, elem := range v {
-			s += fmt.Sprintf("%*s- %s\n", indent+4, "", KvToYaml("", elem, 0))
+			s += seqElemToYaml(elem, indent+4)
 		}
 
-	case map[interface{}]interface{}:
+	case []string:
 		s += "\n"
+		for _, elem := range v {
+			s += seqElemToYaml(elem, indent+4)
+		}
 
-		subKeys := make([]string, 0, len(v))
-		for sk, _ := range v {
-			subKeys = append(subKeys, sk.(string))
+	case map[interface{}]interface{}:
+		sm := make(map[string]interface{}, len(v))
+		for sk, sv := range v {
+			sm[fmt.Sprintf("%v", sk)] = sv
 		}
-		sort.Strings(subKeys)
+		s += "\n" + mapToYaml(sm, indent+4)
+
+	case map[string]interface{}:
+		s += "\n" + mapToYaml(v, indent+4)
 
-		for _, sk := range subKeys {
-			s += KvToYaml(sk, v[sk], indent+4)
+	case map[string]string:
+		sm := make(map[string]interface{}, len(v))
+		for sk, sv := range v {
+			sm[sk] = sv
 		}
+		s += "\n" + mapToYaml(sm, indent+4)
 
 	default:
-		valStr := EscapeString(fmt.Sprintf("%v", v))
-		s += fmt.Sprintf(" %v\n", valStr)
+		s += fmt.Sprintf(" %s\n", scalarToYaml(v))
 	}
 
 	return s
 }
 
 func MapToYaml(m map[string]interface{}) string {
-	keys := make([]string, 0, len(m))
-	for k, _ := range m {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	s := ""
-	for _, k := range keys {
-		s += KvToYaml(k, m[k], 0)
-	}
-
-	return s
+	return mapToYaml(m, 0)
 }